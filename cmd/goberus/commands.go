@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/internal/passwords"
+)
+
+const configUsage = `usage: goberus config print [flags]
+
+Prints the effective configuration (defaults < file < env < flags) as YAML
+with secrets redacted. Accepts the same flags as the server.`
+
+// runConfigCommand implements the "goberus config" subcommands and returns
+// the process exit code.
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		printConfigError(stderr, err)
+		return 1
+	}
+	if err := cfg.WriteYAML(stdout); err != nil {
+		fmt.Fprintf(stderr, "write config: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printConfigError lists validation failures one per line.
+func printConfigError(w io.Writer, err error) {
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		fmt.Fprintf(w, "config: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, "config: invalid configuration:")
+	for _, fe := range verr.Errors {
+		fmt.Fprintf(w, "  - %s\n", fe.Error())
+	}
+}
+
+const passwordsUsage = `usage: goberus passwords build-filter -in FILE -out FILE [-fp RATE]
+
+Compiles a Have I Been Pwned SHA-1 file (one HASH:COUNT line per entry) into
+a compact Bloom filter for BREACHED_PASSWORDS_FILE.`
+
+// runPasswordsCommand implements the "goberus passwords" subcommands and
+// returns the process exit code.
+func runPasswordsCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "build-filter" {
+		fmt.Fprintln(stderr, passwordsUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("build-filter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	in := fs.String("in", "", "HIBP SHA-1 corpus to read")
+	out := fs.String("out", "", "filter file to write")
+	fp := fs.Float64("fp", 0.001, "false positive rate")
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *in == "" || *out == "" {
+		fmt.Fprintln(stderr, passwordsUsage)
+		return 2
+	}
+
+	n, err := countLines(*in)
+	if err != nil {
+		fmt.Fprintf(stderr, "build-filter: %v\n", err)
+		return 1
+	}
+	if err := buildFilter(*in, *out, n, *fp); err != nil {
+		fmt.Fprintf(stderr, "build-filter: %v\n", err)
+		return 1
+	}
+	bits, hashes := passwords.FilterSize(n, *fp)
+	fmt.Fprintf(stdout, "wrote %s: %d entries, %d bytes, %d hashes\n", *out, n, (bits+7)/8, hashes)
+	return 0
+}
+
+func countLines(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var n uint64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) > 0 {
+			n++
+		}
+	}
+	return n, sc.Err()
+}
+
+// buildFilter writes the filter next to out and renames it into place, so
+// a running server never opens a partial file.
+func buildFilter(in, out string, n uint64, fp float64) error {
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), ".goberus-filter-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := passwords.BuildFilter(bufio.NewReader(src), n, fp, w); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,13 +14,39 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/internal/admin"
 	"github.com/lugatuic/goberus/internal/httpserver"
+	"github.com/lugatuic/goberus/internal/listen"
+	"github.com/lugatuic/goberus/internal/logctx"
+	"github.com/lugatuic/goberus/internal/metrics"
+	"github.com/lugatuic/goberus/internal/passwords"
+	"github.com/lugatuic/goberus/internal/rotation"
+	"github.com/lugatuic/goberus/internal/secrets"
+	"github.com/lugatuic/goberus/internal/servertls"
+	"github.com/lugatuic/goberus/internal/tracing"
 	"github.com/lugatuic/goberus/ldaps"
 )
 
+// Version and Commit are injected at build time via -ldflags (see Dockerfile).
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "passwords" {
+		os.Exit(runPasswordsCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize structured logger early so we can log config errors.
-	logger, lerr := zap.NewProduction()
+	// Sensitive fields (passwords, tokens) are redacted at the core so every
+	// derived logger, including request-scoped ones, inherits the policy.
+	// The level is atomic so the admin listener can change it at runtime.
+	zapCfg := zap.NewProductionConfig()
+	logger, lerr := zapCfg.Build(zap.WrapCore(logctx.RedactCore))
 	if lerr != nil {
 		panic("failed to initialize logger: " + lerr.Error())
 	}
@@ -28,25 +56,71 @@ func main() {
 		}
 	}()
 
-	// Load configuration (consider flags + env overrides as a next step).
-	cfg, err := config.LoadFromEnv()
+	// Load configuration: defaults < file (-config / GOBERUS_CONFIG) < env < flags.
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		logger.Fatal("config load failed", zap.Error(err))
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint)
+	if err != nil {
+		logger.Fatal("tracing setup failed", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("tracing.shutdown.error", zap.Error(err))
+		}
+	}()
+
+	if cfg.Transport == config.TransportPlain {
+		logger.Warn("ldap.transport.plaintext", zap.String("ldap_addr", cfg.LdapAddr))
+	}
+
+	m := metrics.New()
+
 	// Initialize dependency clients.
-	client, err := ldaps.NewClient(cfg, logger)
+	clientOpts := []ldaps.Option{ldaps.WithHooks(m)}
+	if cfg.BreachedPasswordsFile != "" {
+		screener, err := passwords.Open(cfg.BreachedPasswordsFile)
+		if err != nil {
+			logger.Fatal("breached password corpus unavailable", zap.Error(err))
+		}
+		defer screener.Close()
+		clientOpts = append(clientOpts, ldaps.WithPasswordScreener(screener))
+	}
+	client, err := ldaps.NewClient(cfg, logger, clientOpts...)
 	if err != nil {
 		logger.Fatal("ldaps client init failed", zap.Error(err))
 	}
 
+	// Warn when the read identity can modify the directory, which defeats
+	// the point of splitting it from the write identity.
+	if cfg.ReadBindDN != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			canWrite, err := client.ReadIdentityCanWrite(ctx)
+			switch {
+			case err != nil:
+				logger.Warn("ldap.read_identity.check_failed", zap.Error(err))
+			case canWrite:
+				logger.Warn("ldap.read_identity.can_write",
+					zap.String("read_bind_dn", cfg.ReadBindDN), zap.String("base_dn", cfg.BaseDN))
+			}
+		}()
+	}
+
 	// Build HTTP handler using Mat Ryer–style server composition.
-	s := httpserver.New(cfg, logger, client)
+	s := httpserver.New(cfg, logger, client, httpserver.WithMetrics(m))
 	handler := s.Handler()
 
 	// Harden the HTTP server with sensible timeouts.
 	srv := &http.Server{
-		Addr:              cfg.BindAddr,
 		Handler:           handler,
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       30 * time.Second,
@@ -54,30 +128,109 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	// Serve HTTPS when a certificate is configured. The certificate and
+	// client CA are re-read on change, independently of config reloads.
+	if cfg.HTTPTLSCert != "" {
+		certs, err := servertls.New(cfg.HTTPTLSCert, cfg.HTTPTLSKey, cfg.HTTPTLSClientCA, cfg.HTTPTLSClientAuth, logger)
+		if err != nil {
+			logger.Fatal("http.tls.init_failed", zap.Error(err))
+		}
+		srv.TLSConfig = certs.TLSConfig()
+	}
+
+	// Listen on a systemd-activated socket, a unix socket or TCP. Shutdown
+	// closes the listener either way; an activated socket stays open in
+	// systemd so connections queue across restarts instead of being refused.
+	mode, err := cfg.SocketMode()
+	if err != nil {
+		logger.Fatal("http.listen.failed", zap.Error(err))
+	}
+	ln, kind, err := listen.Listen(cfg.BindAddr, mode)
+	if err != nil {
+		logger.Fatal("http.listen.failed", zap.Error(err))
+	}
+
 	// Start server in background.
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
-		logger.Info("http.listen", zap.String("addr", cfg.BindAddr))
-		errCh <- srv.ListenAndServe()
+		logger.Info("http.listen", zap.String("addr", ln.Addr().String()), zap.String("listener", kind),
+			zap.Bool("tls", srv.TLSConfig != nil), zap.Bool("mtls", cfg.HTTPTLSClientCA != ""))
+		if srv.TLSConfig != nil {
+			errCh <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errCh <- srv.Serve(ln)
 	}()
 
+	// Optional admin listener (log level, pprof, build info), kept off the
+	// public address and bound to localhost unless configured otherwise.
+	var adminSrv *http.Server
+	if cfg.AdminEnabled {
+		info := admin.ReadBuildInfo(Version, Commit)
+		adminSrv = &http.Server{
+			Addr:              cfg.AdminAddr,
+			Handler:           admin.Handler(zapCfg.Level, info, client),
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		}
+		go func() {
+			logger.Info("admin.listen", zap.String("addr", cfg.AdminAddr), zap.String("version", info.Version))
+			errCh <- adminSrv.ListenAndServe()
+		}()
+	}
+
+	// Reload on SIGHUP (and, if configured, when the config file, CA bundle or
+	// client certificate changes) without interrupting in-flight requests.
+	rl := &reloader{args: os.Args[1:], logger: logger, client: client, startup: cfg, current: cfg}
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if cfg.ReloadInterval > 0 {
+		go rl.watch(watchCtx, cfg.ReloadInterval)
+	}
+
+	// Rotate the service account password in the background. Enable this on
+	// one instance only; the others pick up the new secret on their next
+	// rejected bind.
+	if cfg.BindPasswordRotateInterval > 0 {
+		store, err := secrets.NewStore(cfg.BindPasswordFile, cfg.BindPasswordCommand, cfg.BindPasswordStoreCommand)
+		if err != nil {
+			logger.Fatal("bind_password.rotation.init_failed", zap.Error(err))
+		}
+		rot := rotation.New(client, store, cfg.BindPasswordRotateInterval, logger)
+		go rot.Run(watchCtx)
+	}
+
 	// Graceful shutdown on signals.
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	select {
-	case sig := <-sigCh:
-		logger.Info("shutdown.signal", zap.String("signal", sig.String()))
-		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-		defer cancel()
-		if err := srv.Shutdown(ctx); err != nil {
-			logger.Error("shutdown.error", zap.Error(err))
-		} else {
-			logger.Info("shutdown.complete")
-		}
-	case err := <-errCh:
-		if err != nil && err != http.ErrServerClosed {
-			logger.Fatal("http.server.failed", zap.Error(err))
+	for {
+		select {
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				rl.reload("sighup")
+				continue
+			}
+			logger.Info("shutdown.signal", zap.String("signal", sig.String()))
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
+			if adminSrv != nil {
+				if err := adminSrv.Shutdown(ctx); err != nil {
+					logger.Error("admin.shutdown.error", zap.Error(err))
+				}
+			}
+			if err := srv.Shutdown(ctx); err != nil {
+				logger.Error("shutdown.error", zap.Error(err))
+			} else {
+				logger.Info("shutdown.complete")
+			}
+			client.Close()
+			return
+		case err := <-errCh:
+			if err != nil && err != http.ErrServerClosed {
+				logger.Fatal("http.server.failed", zap.Error(err))
+			}
+			return
 		}
 	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/ldaps"
+)
+
+// reloader re-reads the configuration on SIGHUP (or when watched files
+// change) and swaps it into the LDAP client. An invalid configuration is
+// rejected and the running one is kept.
+type reloader struct {
+	args   []string
+	logger *zap.Logger
+	client *ldaps.Client
+
+	// startup holds the configuration the process was started with, which
+	// static settings keep until a restart.
+	startup *config.Config
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// staticFields are the settings bound at startup: listeners, exporter,
+// rotation, the breached-password file, the readiness certificate warning
+// and the file watch interval.
+var staticFields = []struct {
+	name  string
+	value func(*config.Config) any
+}{
+	{"bind_addr", func(c *config.Config) any { return c.BindAddr }},
+	{"bind_socket_mode", func(c *config.Config) any { return c.BindSocketMode }},
+	{"admin_enabled", func(c *config.Config) any { return c.AdminEnabled }},
+	{"admin_addr", func(c *config.Config) any { return c.AdminAddr }},
+	{"otlp_endpoint", func(c *config.Config) any { return c.OTLPEndpoint }},
+	{"bind_password_rotate_interval", func(c *config.Config) any { return c.BindPasswordRotateInterval }},
+	{"bind_password_store_command", func(c *config.Config) any { return c.BindPasswordStoreCommand }},
+	{"http_tls_cert", func(c *config.Config) any { return c.HTTPTLSCert }},
+	{"http_tls_key", func(c *config.Config) any { return c.HTTPTLSKey }},
+	{"http_tls_client_ca", func(c *config.Config) any { return c.HTTPTLSClientCA }},
+	{"http_tls_client_auth", func(c *config.Config) any { return c.HTTPTLSClientAuth }},
+	{"breached_passwords_file", func(c *config.Config) any { return c.BreachedPasswordsFile }},
+	{"ldap_cert_expiry_warning", func(c *config.Config) any { return c.CertExpiryWarning }},
+	{"reload_interval", func(c *config.Config) any { return c.ReloadInterval }},
+}
+
+// restartRequired lists the static fields of cfg that differ from startup.
+func restartRequired(startup, cfg *config.Config) []string {
+	var fields []string
+	for _, f := range staticFields {
+		if f.value(cfg) != f.value(startup) {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+func (r *reloader) reload(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger := r.logger.With(zap.String("trigger", reason))
+	cfg, err := config.Load(r.args)
+	if err != nil {
+		logger.Error("config.reload.rejected", zap.Error(err))
+		return
+	}
+	if err := r.client.Reload(cfg); err != nil {
+		logger.Error("config.reload.rejected", zap.Error(err))
+		return
+	}
+
+	// Compared with startup, not the last reload, so a change stays
+	// reported until the process is restarted.
+	if fields := restartRequired(r.startup, cfg); len(fields) > 0 {
+		logger.Warn("config.reload.restart_required", zap.Strings("fields", fields))
+	}
+	r.current = cfg
+	logger.Info("config.reload.applied")
+}
+
+// watch polls the config file, CA bundle and client certificate and reloads
+// when any of them changes.
+func (r *reloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := r.fingerprint()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if fp := r.fingerprint(); fp != last {
+				last = fp
+				r.reload("file_change")
+			}
+		}
+	}
+}
+
+// fingerprint summarizes the modification state of the watched files.
+func (r *reloader) fingerprint() string {
+	r.mu.Lock()
+	paths := []string{r.current.File, r.current.CACertPath, r.current.ClientCertPath, r.current.ClientKeyPath}
+	r.mu.Unlock()
+
+	var fp string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil {
+			fp += p + fi.ModTime().String() + "|"
+		}
+	}
+	return fp
+}
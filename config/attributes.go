@@ -0,0 +1,118 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AttributeMapping exposes a directory attribute as a field of the "custom"
+// object in member requests and responses.
+type AttributeMapping struct {
+	// Field is the API name, e.g. "major".
+	Field string `yaml:"field"`
+	// Attribute is the LDAP attribute, e.g. "extensionAttribute1".
+	Attribute string `yaml:"attribute"`
+	// Type is "string" (default), "int" or "bool".
+	Type string `yaml:"type,omitempty"`
+	// MultiValued fields take and return JSON arrays.
+	MultiValued bool `yaml:"multi_valued,omitempty"`
+	// Pattern, if set, is a regular expression every value must match in full.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Access is "read", "write" or "readwrite" (default).
+	Access string `yaml:"access,omitempty"`
+}
+
+// Attribute types.
+const (
+	AttrString = "string"
+	AttrInt    = "int"
+	AttrBool   = "bool"
+)
+
+// Attribute access modes.
+const (
+	AccessRead      = "read"
+	AccessWrite     = "write"
+	AccessReadWrite = "readwrite"
+)
+
+// Readable reports whether the field is returned by lookups.
+func (m AttributeMapping) Readable() bool {
+	return m.Access == "" || m.Access == AccessRead || m.Access == AccessReadWrite
+}
+
+// Writable reports whether callers may set the field.
+func (m AttributeMapping) Writable() bool {
+	return m.Access == "" || m.Access == AccessWrite || m.Access == AccessReadWrite
+}
+
+// DefaultAttributes reproduces the custom fields goberus always supported.
+func DefaultAttributes() []AttributeMapping {
+	return []AttributeMapping{
+		{Field: "major", Attribute: "extensionAttribute1"},
+		{Field: "college", Attribute: "extensionAttribute2"},
+	}
+}
+
+var (
+	fieldName     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	attributeName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+)
+
+// reservedAttributes are managed by goberus itself and cannot be mapped.
+var reservedAttributes = map[string]bool{
+	"objectclass":        true,
+	"cn":                 true,
+	"distinguishedname":  true,
+	"samaccountname":     true,
+	"userprincipalname":  true,
+	"unicodepwd":         true,
+	"useraccountcontrol": true,
+	"memberof":           true,
+}
+
+func validateAttributes(mappings []AttributeMapping, verr *ValidationError) {
+	fields := make(map[string]bool)
+	attrs := make(map[string]bool)
+	for i, m := range mappings {
+		name := "attributes[" + m.Field + "]"
+		if m.Field == "" {
+			name = "attributes[" + strconv.Itoa(i) + "]"
+		}
+		switch {
+		case !fieldName.MatchString(m.Field):
+			verr.add(name, "field must start with a letter and contain only letters, digits and _")
+		case fields[m.Field]:
+			verr.add(name, "field is mapped more than once")
+		}
+		fields[m.Field] = true
+
+		lower := strings.ToLower(m.Attribute)
+		switch {
+		case !attributeName.MatchString(m.Attribute):
+			verr.add(name, "attribute %q is not a valid LDAP attribute name", m.Attribute)
+		case reservedAttributes[lower]:
+			verr.add(name, "attribute %s is managed by goberus and cannot be mapped", m.Attribute)
+		case attrs[lower]:
+			verr.add(name, "attribute %s is mapped more than once", m.Attribute)
+		}
+		attrs[lower] = true
+
+		switch m.Type {
+		case "", AttrString, AttrInt, AttrBool:
+		default:
+			verr.add(name, "type must be %s, %s or %s", AttrString, AttrInt, AttrBool)
+		}
+		switch m.Access {
+		case "", AccessRead, AccessWrite, AccessReadWrite:
+		default:
+			verr.add(name, "access must be %s, %s or %s", AccessRead, AccessWrite, AccessReadWrite)
+		}
+		if m.Pattern != "" {
+			if _, err := regexp.Compile(m.Pattern); err != nil {
+				verr.add(name, "invalid pattern: %v", err)
+			}
+		}
+	}
+}
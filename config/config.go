@@ -3,60 +3,470 @@ package config
 import (
 	"crypto/x509"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/lugatuic/goberus/internal/passwords"
+	"github.com/lugatuic/goberus/internal/usernames"
 )
 
+// Config is the effective service configuration. Each field may be set, in
+// increasing order of precedence, by Default, the YAML file (yaml tag), the
+// environment (env tag) and command-line flags (flag tag). Fields tagged
+// secret are redacted by Redacted and never exposed as flags.
 type Config struct {
-	BindAddr     string // HTTP bind address, e.g. :8080
-	LdapAddr     string // host:port, e.g. dc.example.local:636
-	BaseDN       string
-	BindDN       string
-	BindPassword string
-	SkipVerify   bool
-	CACertPath   string // optional path to CA PEM to verify LDAPS certs
+	BindAddr     string `yaml:"bind_addr" env:"BIND_ADDR" flag:"bind-addr" usage:"HTTP bind address, e.g. :8080"`
+	LdapAddr     string `yaml:"ldap_addr" env:"LDAP_ADDR" flag:"ldap-addr" usage:"LDAPS host:port, e.g. dc.example.local:636"`
+	BaseDN       string `yaml:"base_dn" env:"LDAP_BASE_DN" flag:"base-dn" usage:"base DN for searches and new entries"`
+	BindDN       string `yaml:"bind_dn" env:"LDAP_BIND_DN" flag:"bind-dn" usage:"service account DN"`
+	BindPassword string `yaml:"bind_password" env:"LDAP_BIND_PASSWORD" secret:"true"`
+	// BindPasswordFile and BindPasswordCommand are alternatives to
+	// BindPassword that keep the secret out of the environment. Both are
+	// re-read when a bind is rejected, so rotation needs no restart.
+	BindPasswordFile    string `yaml:"bind_password_file" env:"LDAP_BIND_PASSWORD_FILE" flag:"bind-password-file" usage:"file containing the service account password"`
+	BindPasswordCommand string `yaml:"bind_password_command" env:"LDAP_BIND_PASSWORD_COMMAND" flag:"bind-password-command" usage:"command whose stdout is the service account password"`
+	SkipVerify          bool   `yaml:"skip_verify" env:"LDAP_SKIP_VERIFY" flag:"skip-verify" usage:"skip LDAPS certificate verification (development only)"`
+	CACertPath          string `yaml:"ca_cert" env:"LDAP_CA_CERT" flag:"ca-cert" usage:"path to CA PEM used to verify LDAPS certificates"`
+	OTLPEndpoint        string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT" flag:"otlp-endpoint" usage:"OTLP/HTTP traces URL"`
+	AdminEnabled        bool   `yaml:"admin_enabled" env:"ADMIN_ENABLED" flag:"admin-enabled" usage:"serve log level, pprof and build info on admin-addr"`
+	AdminAddr           string `yaml:"admin_addr" env:"ADMIN_ADDR" flag:"admin-addr" usage:"admin listen address"`
+
+	PoolSize        int           `yaml:"ldap_pool_size" env:"LDAP_POOL_SIZE" flag:"ldap-pool-size" usage:"maximum idle LDAP connections kept for reuse"`
+	PoolIdleTimeout time.Duration `yaml:"ldap_pool_idle_timeout" env:"LDAP_POOL_IDLE_TIMEOUT" flag:"ldap-pool-idle-timeout" usage:"close pooled LDAP connections idle longer than this"`
+	ReloadInterval  time.Duration `yaml:"reload_interval" env:"RELOAD_INTERVAL" flag:"reload-interval" usage:"poll the config file and TLS certificate files for changes at this interval (0 disables; SIGHUP always reloads)"`
+
+	// BindSocketMode sets the permissions of a unix socket BindAddr
+	// ("unix:/path"), as an octal string.
+	BindSocketMode string `yaml:"bind_socket_mode" env:"BIND_SOCKET_MODE" flag:"bind-socket-mode" usage:"octal permissions for a unix:/path bind-addr socket"`
+
+	// HTTPTLSCert and HTTPTLSKey serve the API over HTTPS; the files are
+	// re-read when they change. HTTPTLSClientCA enables client certificates,
+	// whose identity becomes the request actor.
+	HTTPTLSCert       string `yaml:"http_tls_cert" env:"HTTP_TLS_CERT" flag:"http-tls-cert" usage:"PEM certificate for serving HTTPS"`
+	HTTPTLSKey        string `yaml:"http_tls_key" env:"HTTP_TLS_KEY" flag:"http-tls-key" usage:"PEM private key for http-tls-cert"`
+	HTTPTLSClientCA   string `yaml:"http_tls_client_ca" env:"HTTP_TLS_CLIENT_CA" flag:"http-tls-client-ca" usage:"PEM CA bundle used to verify client certificates (enables mTLS)"`
+	HTTPTLSClientAuth string `yaml:"http_tls_client_auth" env:"HTTP_TLS_CLIENT_AUTH" flag:"http-tls-client-auth" usage:"client certificate policy with http-tls-client-ca: require or optional"`
+
+	// Profile is "development" or "production"; production refuses settings
+	// that are only safe on a developer machine, such as SkipVerify.
+	Profile string `yaml:"profile" env:"GOBERUS_PROFILE" flag:"profile" usage:"deployment profile: development or production"`
+
+	// Transport is how the directory connection is secured: "ldaps" (TLS
+	// from the first byte), "starttls" (upgrade on the plain LDAP port) or
+	// "ldap" (no encryption), which also requires AllowPlaintext and is
+	// refused in production. The TLS settings below apply to both TLS modes.
+	Transport      string `yaml:"ldap_transport" env:"LDAP_TRANSPORT" flag:"ldap-transport" usage:"ldaps, starttls or ldap (plaintext, development only)"`
+	AllowPlaintext bool   `yaml:"ldap_allow_plaintext" env:"LDAP_ALLOW_PLAINTEXT" flag:"ldap-allow-plaintext" usage:"permit ldap-transport ldap, sending binds and data unencrypted"`
+
+	// TLS settings for the directory connection. SPKI pins are a safer
+	// alternative to SkipVerify for DCs with self-signed certificates: without
+	// a CA they replace chain verification, with one they add to it. A client
+	// certificate enables mutual TLS, and BindMechanism "external" binds with
+	// SASL EXTERNAL as the certificate's identity instead of a password.
+	TLSServerName   string   `yaml:"ldap_tls_server_name" env:"LDAP_TLS_SERVER_NAME" flag:"ldap-tls-server-name" usage:"expected server certificate name when it differs from the ldap-addr host"`
+	TLSMinVersion   string   `yaml:"ldap_tls_min_version" env:"LDAP_TLS_MIN_VERSION" flag:"ldap-tls-min-version" usage:"minimum TLS version: 1.2 or 1.3"`
+	TLSMaxVersion   string   `yaml:"ldap_tls_max_version" env:"LDAP_TLS_MAX_VERSION" flag:"ldap-tls-max-version" usage:"maximum TLS version: 1.2 or 1.3 (default: highest supported)"`
+	TLSCipherSuites []string `yaml:"ldap_tls_cipher_suites" env:"LDAP_TLS_CIPHER_SUITES" flag:"ldap-tls-cipher-suites" usage:"comma-separated TLS 1.2 cipher suite names (default: Go's secure defaults)"`
+	TLSPinnedSPKI   []string `yaml:"ldap_tls_pinned_spki" env:"LDAP_TLS_PINNED_SPKI" flag:"ldap-tls-pinned-spki" usage:"comma-separated base64 SHA-256 SPKI hashes the DC certificate must match"`
+	ClientCertPath  string   `yaml:"ldap_client_cert" env:"LDAP_CLIENT_CERT" flag:"ldap-client-cert" usage:"PEM client certificate presented to the DC"`
+	ClientKeyPath   string   `yaml:"ldap_client_key" env:"LDAP_CLIENT_KEY" flag:"ldap-client-key" usage:"PEM private key for ldap-client-cert"`
+	BindMechanism   string   `yaml:"bind_mechanism" env:"LDAP_BIND_MECHANISM" flag:"bind-mechanism" usage:"service bind mechanism: simple or external (SASL EXTERNAL with the client certificate)"`
+
+	// CertExpiryWarning is how long before the directory certificate
+	// expires /readyz starts reporting a warning.
+	CertExpiryWarning time.Duration `yaml:"ldap_cert_expiry_warning" env:"LDAP_CERT_EXPIRY_WARNING" flag:"ldap-cert-expiry-warning" usage:"warn in /readyz when the LDAP server certificate expires within this window"`
+
+	// ReadBindDN, when set, is a separate least-privilege identity used for
+	// lookups and readiness probes; BindDN is then used only for writes.
+	// Without it every operation binds as BindDN.
+	ReadBindDN              string `yaml:"read_bind_dn" env:"LDAP_READ_BIND_DN" flag:"read-bind-dn" usage:"read-only service account DN for lookups and probes"`
+	ReadBindPassword        string `yaml:"read_bind_password" env:"LDAP_READ_BIND_PASSWORD" secret:"true"`
+	ReadBindPasswordFile    string `yaml:"read_bind_password_file" env:"LDAP_READ_BIND_PASSWORD_FILE" flag:"read-bind-password-file" usage:"file containing the read-only service account password"`
+	ReadBindPasswordCommand string `yaml:"read_bind_password_command" env:"LDAP_READ_BIND_PASSWORD_COMMAND" flag:"read-bind-password-command" usage:"command whose stdout is the read-only service account password"`
+
+	// BindPasswordRotateInterval enables automatic rotation of the service
+	// account password once it is older than this. Rotation writes the new
+	// secret back to BindPasswordFile, or via BindPasswordStoreCommand when
+	// the secret comes from BindPasswordCommand.
+	BindPasswordRotateInterval time.Duration `yaml:"bind_password_rotate_interval" env:"LDAP_BIND_PASSWORD_ROTATE_INTERVAL" flag:"bind-password-rotate-interval" usage:"rotate the service account password when older than this (0 disables)"`
+	BindPasswordStoreCommand   string        `yaml:"bind_password_store_command" env:"LDAP_BIND_PASSWORD_STORE_COMMAND" flag:"bind-password-store-command" usage:"command that stores a rotated service account password read from stdin"`
+
+	// BreachedPasswordsFile is a breached-password corpus every password
+	// set through goberus is screened against: a sorted HIBP SHA-1 file or
+	// a filter built by "goberus passwords build-filter".
+	BreachedPasswordsFile string `yaml:"breached_passwords_file" env:"BREACHED_PASSWORDS_FILE" flag:"breached-passwords-file" usage:"breached-password corpus (sorted HIBP SHA-1 file or filter) to screen passwords against"`
+
+	// Generated passwords for new members are PasswordLength characters from
+	// PasswordClasses, or passphrases of PasswordWords words when
+	// PasswordWordlist is set; either grows to the domain's minimum length.
+	PasswordLength   int    `yaml:"password_length" env:"PASSWORD_LENGTH" flag:"password-length" usage:"length of generated passwords"`
+	PasswordClasses  string `yaml:"password_classes" env:"PASSWORD_CLASSES" flag:"password-classes" usage:"character classes of generated passwords: lower,upper,digit,symbol"`
+	PasswordWordlist string `yaml:"password_wordlist" env:"PASSWORD_WORDLIST" flag:"password-wordlist" usage:"wordlist file; generate passphrases instead of random characters"`
+	PasswordWords    int    `yaml:"password_words" env:"PASSWORD_WORDS" flag:"password-words" usage:"minimum words in a generated passphrase"`
+
+	// ReservedUsernames can never be registered or suggested. Username
+	// suggestions are derived from UsernamePatterns, comma-separated
+	// patterns over {first}, {last}, {f}, {l} (initials) and {netid}.
+	ReservedUsernames string `yaml:"reserved_usernames" env:"RESERVED_USERNAMES" flag:"reserved-usernames" usage:"comma-separated usernames that cannot be registered"`
+	UsernamePatterns  string `yaml:"username_patterns" env:"USERNAME_PATTERNS" flag:"username-patterns" usage:"comma-separated patterns for username suggestions"`
+
+	// UPNSuffix is the userPrincipalName suffix of new accounts; empty
+	// derives it from BaseDN. UPNSuffixes are further suffixes callers may
+	// choose, in addition to the forest's uPNSuffixes.
+	UPNSuffix   string   `yaml:"upn_suffix" env:"UPN_SUFFIX" flag:"upn-suffix" usage:"userPrincipalName suffix of new accounts (default: derived from the base DN)"`
+	UPNSuffixes []string `yaml:"upn_suffixes" env:"UPN_SUFFIXES" flag:"upn-suffixes" usage:"comma-separated alternative UPN suffixes callers may choose"`
+
+	// UniqueAttributes are attributes no two accounts may share, checked
+	// before every create. AD itself only enforces sAMAccountName.
+	UniqueAttributes string `yaml:"unique_attributes" env:"UNIQUE_ATTRIBUTES" flag:"unique-attributes" usage:"comma-separated attributes that must be unique across accounts"`
+
+	// Attributes maps API fields under "custom" to directory attributes.
+	// They can only be set in the config file.
+	Attributes []AttributeMapping `yaml:"attributes"`
+
+	// AllowedOUs, when set, are the only OUs new accounts may be created in
+	// besides template OUs; otherwise a requested OU must exist. DNs contain
+	// commas, so the list can only be set in the config file.
+	AllowedOUs []string `yaml:"allowed_ous,omitempty"`
+
+	// Templates are the named provisioning templates, also file-only.
+	// DefaultTemplate applies when a request names none.
+	Templates       map[string]Template `yaml:"templates,omitempty"`
+	DefaultTemplate string              `yaml:"default_template" env:"GOBERUS_DEFAULT_TEMPLATE" flag:"default-template" usage:"provisioning template used when a request names none"`
+
+	// File is the config file the configuration was loaded from, if any.
+	File string `yaml:"-"`
 }
 
+// Deployment profiles.
+const (
+	ProfileDevelopment = "development"
+	ProfileProduction  = "production"
+)
+
+// Directory transports.
+const (
+	TransportLDAPS    = "ldaps"
+	TransportStartTLS = "starttls"
+	TransportPlain    = "ldap"
+)
+
+// Service bind mechanisms.
+const (
+	BindSimple   = "simple"
+	BindExternal = "external"
+)
+
+// dnsName matches a DNS domain such as lug.example.
+var dnsName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// Default returns the built-in defaults, the lowest configuration layer.
+func Default() *Config {
+	return &Config{
+		BindAddr:  ":8080",
+		LdapAddr:  "dc.example.local:636",
+		BaseDN:    "dc=example,dc=local",
+		AdminAddr: "127.0.0.1:9090",
+		Profile:   ProfileDevelopment,
+
+		BindSocketMode: "0660",
+
+		HTTPTLSClientAuth: "require",
+
+		Transport:     TransportLDAPS,
+		TLSMinVersion: "1.2",
+		BindMechanism: BindSimple,
+
+		CertExpiryWarning: 14 * 24 * time.Hour,
+
+		PasswordLength:  16,
+		PasswordClasses: "lower,upper,digit,symbol",
+		PasswordWords:   5,
+
+		ReservedUsernames: "admin,administrator,root,krbtgt,guest,president,vicepresident,treasurer,secretary,officers,sysadmin,webmaster",
+		UsernamePatterns:  "{netid},{f}{last},{first}.{last},{first}{l},{first}_{last}",
+
+		UniqueAttributes: "mail,userPrincipalName",
+
+		Attributes: DefaultAttributes(),
+
+		PoolSize:        4,
+		PoolIdleTimeout: 5 * time.Minute,
+	}
+}
+
+// LoadFromEnv returns defaults overridden by the environment (including a
+// config file named by GOBERUS_CONFIG). It is Load without flags.
 func LoadFromEnv() (*Config, error) {
-	var cfg *Config = &Config{
-		BindAddr:     getenv("BIND_ADDR", ":8080"),
-		LdapAddr:     getenv("LDAP_ADDR", "dc.example.local:636"),
-		BaseDN:       getenv("LDAP_BASE_DN", "dc=example,dc=local"),
-		BindDN:       os.Getenv("LDAP_BIND_DN"),
-		BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
-		CACertPath:   os.Getenv("LDAP_CA_CERT"),
-	}
-	cfg.SkipVerify = boolFromEnv("LDAP_SKIP_VERIFY", false)
-	// CA cert path is optional; if provided, it will be validated at connection time.
-	// Do not check existence here to support containers where the CA file may not be
-	// available immediately at startup (e.g., Samba initialization in docker-compose).
-	// Basic validation
-	if cfg.BaseDN == "" {
-		return nil, fmt.Errorf("LDAP_BASE_DN must be set")
-	}
-	return cfg, nil
+	return Load(nil)
+}
+
+// FieldError describes one invalid configuration field.
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationError collects every invalid field so operators can fix them in
+// one pass instead of one restart per mistake.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (e *ValidationError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
 }
 
-func boolFromEnv(key string, def bool) bool {
-	val, ok := os.LookupEnv(key)
-	if !ok || val == "" {
-		return def
+// Validate reports every invalid field at once.
+func (c *Config) Validate() error {
+	verr := &ValidationError{}
+	c.validate(verr)
+	return verr.errOrNil()
+}
+
+func (c *Config) validate(verr *ValidationError) {
+	if c.BindAddr == "" {
+		verr.add("bind_addr", "must be set")
+	}
+	if _, err := c.SocketMode(); err != nil {
+		verr.add("bind_socket_mode", "%v", err)
+	}
+	if _, _, err := net.SplitHostPort(c.LdapAddr); err != nil {
+		verr.add("ldap_addr", "must be host:port: %v", err)
+	}
+	if c.BaseDN == "" {
+		verr.add("base_dn", "must be set")
+	} else if _, err := ldap.ParseDN(c.BaseDN); err != nil {
+		verr.add("base_dn", "invalid DN: %v", err)
+	}
+	if c.BindDN != "" {
+		if _, err := ldap.ParseDN(c.BindDN); err != nil {
+			verr.add("bind_dn", "invalid DN: %v", err)
+		}
+		if c.BindMechanism != BindExternal && c.BindPassword == "" && c.BindPasswordFile == "" && c.BindPasswordCommand == "" {
+			verr.add("bind_password", "one of bind_password, bind_password_file or bind_password_command must be set when bind_dn is set")
+		}
+	}
+	if countSet(c.BindPassword, c.BindPasswordFile, c.BindPasswordCommand) > 1 {
+		verr.add("bind_password", "only one of bind_password, bind_password_file or bind_password_command may be set")
+	}
+	if c.ReadBindDN != "" {
+		if _, err := ldap.ParseDN(c.ReadBindDN); err != nil {
+			verr.add("read_bind_dn", "invalid DN: %v", err)
+		}
+		if c.ReadBindPassword == "" && c.ReadBindPasswordFile == "" && c.ReadBindPasswordCommand == "" {
+			verr.add("read_bind_password", "one of read_bind_password, read_bind_password_file or read_bind_password_command must be set when read_bind_dn is set")
+		}
+	} else if countSet(c.ReadBindPassword, c.ReadBindPasswordFile, c.ReadBindPasswordCommand) > 0 {
+		verr.add("read_bind_dn", "must be set when a read bind password is configured")
+	}
+	if countSet(c.ReadBindPassword, c.ReadBindPasswordFile, c.ReadBindPasswordCommand) > 1 {
+		verr.add("read_bind_password", "only one of read_bind_password, read_bind_password_file or read_bind_password_command may be set")
+	}
+	if c.BindPasswordRotateInterval < 0 {
+		verr.add("bind_password_rotate_interval", "must not be negative")
+	}
+	if c.BindPasswordRotateInterval > 0 {
+		if c.BindMechanism == BindExternal {
+			verr.add("bind_password_rotate_interval", "does not apply with bind_mechanism external")
+		}
+		if c.BindDN == "" {
+			verr.add("bind_password_rotate_interval", "requires bind_dn")
+		}
+		if c.BindPasswordFile == "" && (c.BindPasswordCommand == "" || c.BindPasswordStoreCommand == "") {
+			verr.add("bind_password_rotate_interval", "requires bind_password_file, or bind_password_command together with bind_password_store_command")
+		}
+	}
+	if c.BindPasswordStoreCommand != "" && c.BindPasswordCommand == "" {
+		verr.add("bind_password_store_command", "requires bind_password_command")
+	}
+	for _, cmd := range []struct{ field, value string }{
+		{"bind_password_command", c.BindPasswordCommand},
+		{"read_bind_password_command", c.ReadBindPasswordCommand},
+		{"bind_password_store_command", c.BindPasswordStoreCommand},
+	} {
+		if cmd.value != "" && strings.TrimSpace(cmd.value) == "" {
+			verr.add(cmd.field, "must name a command")
+		}
+	}
+	c.validateTLS(verr)
+	if c.OTLPEndpoint != "" {
+		u, err := url.Parse(c.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			verr.add("otlp_endpoint", "must be an http(s) URL")
+		}
+	}
+	if (c.HTTPTLSCert == "") != (c.HTTPTLSKey == "") {
+		verr.add("http_tls_cert", "http_tls_cert and http_tls_key must be set together")
+	}
+	if c.HTTPTLSClientCA != "" && c.HTTPTLSCert == "" {
+		verr.add("http_tls_client_ca", "requires http_tls_cert and http_tls_key")
+	}
+	if c.HTTPTLSClientAuth != "require" && c.HTTPTLSClientAuth != "optional" {
+		verr.add("http_tls_client_auth", "must be require or optional")
+	}
+	if c.AdminEnabled {
+		if _, _, err := net.SplitHostPort(c.AdminAddr); err != nil {
+			verr.add("admin_addr", "must be host:port: %v", err)
+		}
+	}
+	if c.PoolSize < 0 {
+		verr.add("ldap_pool_size", "must not be negative")
+	}
+	if c.PoolIdleTimeout < 0 {
+		verr.add("ldap_pool_idle_timeout", "must not be negative")
+	}
+	if c.PasswordLength < 8 || c.PasswordLength > 256 {
+		verr.add("password_length", "must be between 8 and 256")
 	}
-	trimmed := strings.Trim(val, "\"'")
-	b, err := strconv.ParseBool(trimmed)
+	if _, err := passwords.ParseClasses(c.PasswordClasses); err != nil {
+		verr.add("password_classes", "%v", err)
+	}
+	if c.PasswordWords < 3 {
+		verr.add("password_words", "must be at least 3")
+	}
+	if _, err := usernames.ParsePatterns(c.UsernamePatterns); err != nil {
+		verr.add("username_patterns", "%v", err)
+	}
+	if c.UPNSuffix != "" && !dnsName.MatchString(c.UPNSuffix) {
+		verr.add("upn_suffix", "%q is not a DNS domain", c.UPNSuffix)
+	}
+	for _, suffix := range c.UPNSuffixes {
+		if !dnsName.MatchString(suffix) {
+			verr.add("upn_suffixes", "%q is not a DNS domain", suffix)
+		}
+	}
+	for _, name := range strings.Split(c.UniqueAttributes, ",") {
+		if name = strings.TrimSpace(name); name != "" && !attributeName.MatchString(name) {
+			verr.add("unique_attributes", "%q is not an LDAP attribute name", name)
+		}
+	}
+	base, _ := ldap.ParseDN(c.BaseDN)
+	for _, ou := range c.AllowedOUs {
+		dn, err := ldap.ParseDN(ou)
+		switch {
+		case err != nil:
+			verr.add("allowed_ous", "%q is not a valid DN: %v", ou, err)
+		case base != nil && !dn.EqualFold(base) && !base.AncestorOfFold(dn):
+			verr.add("allowed_ous", "%q is not under base_dn", ou)
+		}
+	}
+	validateAttributes(c.Attributes, verr)
+	validateTemplates(c.Templates, c.DefaultTemplate, verr)
+	if c.CertExpiryWarning < 0 {
+		verr.add("ldap_cert_expiry_warning", "must not be negative")
+	}
+	if c.ReloadInterval < 0 {
+		verr.add("reload_interval", "must not be negative")
+	}
+}
+
+func (c *Config) validateTLS(verr *ValidationError) {
+	switch c.Profile {
+	case ProfileDevelopment:
+	case ProfileProduction:
+		if c.SkipVerify {
+			verr.add("skip_verify", "must not be set with profile production; use ca_cert or ldap_tls_pinned_spki")
+		}
+	default:
+		verr.add("profile", "must be %s or %s", ProfileDevelopment, ProfileProduction)
+	}
+	switch c.Transport {
+	case TransportLDAPS, TransportStartTLS:
+	case TransportPlain:
+		if !c.AllowPlaintext {
+			verr.add("ldap_transport", "ldap sends credentials unencrypted; set ldap_allow_plaintext to permit it")
+		}
+		if c.Profile == ProfileProduction {
+			verr.add("ldap_transport", "ldap must not be used with profile production")
+		}
+		if c.BindMechanism == BindExternal {
+			verr.add("bind_mechanism", "external requires a TLS transport")
+		}
+	default:
+		verr.add("ldap_transport", "must be %s, %s or %s", TransportLDAPS, TransportStartTLS, TransportPlain)
+	}
+	minV, err := TLSVersion(c.TLSMinVersion)
+	if err != nil {
+		verr.add("ldap_tls_min_version", "%v", err)
+	}
+	maxV, err := TLSVersion(c.TLSMaxVersion)
 	if err != nil {
-		return def
+		verr.add("ldap_tls_max_version", "%v", err)
+	}
+	if minV != 0 && maxV != 0 && minV > maxV {
+		verr.add("ldap_tls_max_version", "must not be lower than ldap_tls_min_version")
+	}
+	if _, err := CipherSuites(c.TLSCipherSuites); err != nil {
+		verr.add("ldap_tls_cipher_suites", "%v", err)
+	}
+	if _, err := SPKIPins(c.TLSPinnedSPKI); err != nil {
+		verr.add("ldap_tls_pinned_spki", "%v", err)
+	}
+	if c.SkipVerify && len(c.TLSPinnedSPKI) > 0 {
+		verr.add("skip_verify", "must not be combined with ldap_tls_pinned_spki")
+	}
+	if (c.ClientCertPath == "") != (c.ClientKeyPath == "") {
+		verr.add("ldap_client_cert", "ldap_client_cert and ldap_client_key must be set together")
+	}
+	switch c.BindMechanism {
+	case BindSimple:
+	case BindExternal:
+		if c.ClientCertPath == "" {
+			verr.add("bind_mechanism", "external requires ldap_client_cert and ldap_client_key")
+		}
+		if countSet(c.BindPassword, c.BindPasswordFile, c.BindPasswordCommand) > 0 {
+			verr.add("bind_password", "must not be set with bind_mechanism external")
+		}
+	default:
+		verr.add("bind_mechanism", "must be %s or %s", BindSimple, BindExternal)
+	}
+}
+
+// SocketMode parses BindSocketMode.
+func (c *Config) SocketMode() (fs.FileMode, error) {
+	mode, err := strconv.ParseUint(c.BindSocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid octal permissions %q", c.BindSocketMode)
 	}
-	return b
+	return fs.FileMode(mode), nil
 }
 
-func getenv(k, def string) string {
-	var v string = os.Getenv(k)
-	if v == "" {
-		return def
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
 	}
-	return v
+	return n
 }
 
 // helper to load CA pool — callers can use this to build tls.Config
@@ -0,0 +1,309 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+func writeFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "goberus.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	is := is.New(t)
+	path := writeFile(t, `
+bind_addr: ":7000"
+ldap_addr: file.example:636
+base_dn: dc=file,dc=example
+`)
+	t.Setenv(config.FileEnv, "")
+	t.Setenv("LDAP_ADDR", "env.example:636")
+	t.Setenv("LDAP_SKIP_VERIFY", `"true"`)
+
+	cfg, err := config.Load([]string{"-config", path, "-bind-addr", ":9000"})
+	is.NoErr(err)
+	is.Equal(cfg.BindAddr, ":9000")            // flag beats file
+	is.Equal(cfg.LdapAddr, "env.example:636")  // env beats file
+	is.Equal(cfg.BaseDN, "dc=file,dc=example") // file beats default
+	is.Equal(cfg.AdminAddr, "127.0.0.1:9090")  // default
+	is.True(cfg.SkipVerify)                    // quoted booleans are accepted
+}
+
+func TestLoadReportsEveryInvalidField(t *testing.T) {
+	is := is.New(t)
+	t.Setenv(config.FileEnv, "")
+	t.Setenv("LDAP_SKIP_VERIFY", "maybe")
+	t.Setenv("LDAP_ADDR", "no-port")
+	t.Setenv("LDAP_BIND_DN", "cn=svc,dc=example,dc=local")
+
+	_, err := config.Load([]string{"-base-dn", ""})
+	var verr *config.ValidationError
+	is.True(errors.As(err, &verr))
+
+	var fields []string
+	for _, fe := range verr.Errors {
+		fields = append(fields, fe.Field)
+	}
+	is.Equal(fields, []string{"skip_verify", "ldap_addr", "base_dn", "bind_password"})
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	is := is.New(t)
+	t.Setenv(config.FileEnv, writeFile(t, "base_dnn: dc=typo\n"))
+
+	_, err := config.Load(nil)
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "base_dnn"))
+}
+
+func TestWriteYAMLRedactsSecrets(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.BindPassword = "hunter2"
+
+	var b strings.Builder
+	is.NoErr(cfg.WriteYAML(&b))
+	is.True(!strings.Contains(b.String(), "hunter2"))
+	is.True(strings.Contains(b.String(), "bind_password: '[REDACTED]'"))
+	is.Equal(cfg.BindPassword, "hunter2") // original untouched
+}
+
+func TestValidateReadIdentity(t *testing.T) {
+	t.Run("read password requires a read DN", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.ReadBindPassword = "r"
+
+		var verr *config.ValidationError
+		is.True(errors.As(cfg.Validate(), &verr))
+		is.Equal(verr.Errors[0].Field, "read_bind_dn")
+	})
+
+	t.Run("read DN requires a password", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.ReadBindDN = "cn=reader,dc=example,dc=local"
+
+		var verr *config.ValidationError
+		is.True(errors.As(cfg.Validate(), &verr))
+		is.Equal(verr.Errors[0].Field, "read_bind_password")
+	})
+
+	t.Run("complete read identity is valid", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.ReadBindDN = "cn=reader,dc=example,dc=local"
+		cfg.ReadBindPasswordFile = "/run/secrets/read_password"
+		is.NoErr(cfg.Validate())
+	})
+}
+
+func TestValidateBlankCommand(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.BindDN = "cn=svc,dc=example,dc=local"
+	cfg.BindPasswordCommand = " "
+
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(len(verr.Errors), 1)
+	is.Equal(verr.Errors[0].Field, "bind_password_command")
+}
+
+func TestValidateProductionProfile(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.Profile = config.ProfileProduction
+	is.NoErr(cfg.Validate())
+
+	cfg.SkipVerify = true
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "skip_verify")
+}
+
+func TestValidateExternalBind(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.BindMechanism = config.BindExternal
+	cfg.BindDN = "cn=svc,dc=example,dc=local"
+
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "bind_mechanism") // needs a client certificate
+
+	cfg.ClientCertPath = "/etc/goberus/client.pem"
+	cfg.ClientKeyPath = "/etc/goberus/client.key"
+	is.NoErr(cfg.Validate()) // no password required
+}
+
+func TestValidatePlaintextTransport(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.Transport = config.TransportPlain
+
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "ldap_transport") // needs the explicit opt-in
+
+	cfg.AllowPlaintext = true
+	is.NoErr(cfg.Validate())
+
+	cfg.Profile = config.ProfileProduction
+	is.True(cfg.Validate() != nil)
+}
+
+func TestValidateUsernamePatterns(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	is.NoErr(cfg.Validate())
+
+	cfg.UsernamePatterns = "{first}.{surname}"
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "username_patterns")
+}
+
+func TestValidateUniqueAttributes(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.UniqueAttributes = "mail, extensionAttribute2"
+	is.NoErr(cfg.Validate())
+
+	cfg.UniqueAttributes = "mail,(uin)"
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "unique_attributes")
+}
+
+func TestValidateAllowedOUs(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.AllowedOUs = []string{"OU=Members,DC=example,DC=local", "DC=Example,DC=Local"}
+	is.NoErr(cfg.Validate())
+
+	cfg.AllowedOUs = []string{"OU=Members,DC=other,DC=org", "OU=Members,,"}
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(len(verr.Errors), 2)
+	is.Equal(verr.Errors[0].Field, "allowed_ous")
+}
+
+func TestValidateUPNSuffixes(t *testing.T) {
+	is := is.New(t)
+	cfg := config.Default()
+	cfg.UPNSuffix = "lug.example"
+	cfg.UPNSuffixes = []string{"acm.example"}
+	is.NoErr(cfg.Validate())
+
+	cfg.UPNSuffixes = []string{"@acm.example"}
+	var verr *config.ValidationError
+	is.True(errors.As(cfg.Validate(), &verr))
+	is.Equal(verr.Errors[0].Field, "upn_suffixes")
+}
+
+func TestLoadAttributeMappings(t *testing.T) {
+	t.Run("file replaces defaults", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv(config.FileEnv, writeFile(t, `
+attributes:
+  - field: employeeNumber
+    attribute: employeeNumber
+    pattern: "[0-9]{6}"
+  - field: badges
+    attribute: extensionAttribute5
+    multi_valued: true
+    access: read
+`))
+
+		cfg, err := config.Load(nil)
+		is.NoErr(err)
+		is.Equal(len(cfg.Attributes), 2)
+		is.Equal(cfg.Attributes[0].Pattern, "[0-9]{6}")
+		is.True(cfg.Attributes[1].MultiValued)
+		is.True(!cfg.Attributes[1].Writable())
+	})
+
+	t.Run("invalid mappings", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.Attributes = []config.AttributeMapping{
+			{Field: "major", Attribute: "extensionAttribute1"},
+			{Field: "major", Attribute: "unicodePwd"},
+			{Field: "year", Attribute: "extensionAttribute3", Type: "date"},
+			{Field: "code", Attribute: "extensionAttribute4", Pattern: "("},
+		}
+
+		var verr *config.ValidationError
+		is.True(errors.As(cfg.Validate(), &verr))
+		var reasons []string
+		for _, fe := range verr.Errors {
+			reasons = append(reasons, fe.Error())
+		}
+		is.Equal(len(reasons), 4)
+		is.True(strings.Contains(reasons[0], "mapped more than once"))
+		is.True(strings.Contains(reasons[1], "managed by goberus"))
+		is.True(strings.Contains(reasons[2], "type must be"))
+		is.True(strings.Contains(reasons[3], "invalid pattern"))
+	})
+}
+
+func TestValidateTemplates(t *testing.T) {
+	t.Run("file template is loaded", func(t *testing.T) {
+		is := is.New(t)
+		t.Setenv(config.FileEnv, writeFile(t, `
+default_template: student
+templates:
+  student:
+    defaults:
+      company: [LUG]
+    computed:
+      displayName: "{{.GivenName}} {{.Surname}}"
+    ou: OU=Students
+    groups:
+      - CN=Members,DC=example,DC=local
+`))
+
+		cfg, err := config.Load(nil)
+		is.NoErr(err)
+		is.Equal(cfg.Templates["student"].Defaults["company"], []string{"LUG"})
+		is.Equal(cfg.Templates["student"].Groups, []string{"CN=Members,DC=example,DC=local"})
+	})
+
+	t.Run("invalid templates", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.DefaultTemplate = "staff"
+		cfg.Templates = map[string]config.Template{
+			"student": {
+				Defaults: map[string][]string{"unicodePwd": {"x"}},
+				Computed: map[string]string{"displayName": "{{.GivenName"},
+				Groups:   []string{"not a dn"},
+			},
+		}
+
+		var verr *config.ValidationError
+		is.True(errors.As(cfg.Validate(), &verr))
+		var msgs []string
+		for _, fe := range verr.Errors {
+			msgs = append(msgs, fe.Error())
+		}
+		is.Equal(len(msgs), 4)
+		is.True(strings.Contains(msgs[0], "managed by goberus"))
+		is.True(strings.Contains(msgs[1], "computed displayName"))
+		is.True(strings.Contains(msgs[2], "not a valid DN"))
+		is.True(strings.HasPrefix(msgs[3], "default_template"))
+	})
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable that points at a YAML config file.
+// The -config flag takes precedence over it.
+const FileEnv = "GOBERUS_CONFIG"
+
+// redacted replaces secret values in Redacted output.
+const redacted = "[REDACTED]"
+
+// Load builds the effective configuration, layering defaults < YAML file <
+// environment < command-line flags. args are the command-line arguments
+// without the program name. Every invalid field is reported in a single
+// *ValidationError.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("goberus", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(FileEnv), "path to YAML config file (env "+FileEnv+")")
+	overrides := registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := loadFile(*path, cfg); err != nil {
+			return nil, err
+		}
+		cfg.File = *path
+	}
+
+	verr := &ValidationError{}
+	applyEnv(cfg, verr)
+	overrides.apply(cfg, verr)
+	cfg.validate(verr)
+	if err := verr.errOrNil(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields whose env variable is set and non-empty.
+func applyEnv(cfg *Config, verr *ValidationError) {
+	visitFields(cfg, func(f reflect.StructField, v reflect.Value) {
+		key := f.Tag.Get("env")
+		if key == "" {
+			return
+		}
+		raw, ok := os.LookupEnv(key)
+		if !ok || raw == "" {
+			return
+		}
+		if err := setField(v, raw); err != nil {
+			verr.add(yamlName(f), "env %s: %v", key, err)
+		}
+	})
+}
+
+type override struct {
+	field reflect.StructField
+	flag  string
+	raw   string
+}
+
+// flagOverrides records flags in the order given so they can be applied
+// after the file and env layers.
+type flagOverrides struct {
+	set []override
+}
+
+func (o *flagOverrides) apply(cfg *Config, verr *ValidationError) {
+	for _, ov := range o.set {
+		v := reflect.ValueOf(cfg).Elem().FieldByIndex(ov.field.Index)
+		if err := setField(v, ov.raw); err != nil {
+			verr.add(yamlName(ov.field), "flag -%s: %v", ov.flag, err)
+		}
+	}
+}
+
+type fieldFlag struct {
+	overrides *flagOverrides
+	field     reflect.StructField
+	name      string
+	isBool    bool
+}
+
+func (f *fieldFlag) String() string { return "" }
+
+func (f *fieldFlag) Set(raw string) error {
+	f.overrides.set = append(f.overrides.set, override{field: f.field, flag: f.name, raw: raw})
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool { return f.isBool }
+
+func registerFlags(fs *flag.FlagSet) *flagOverrides {
+	o := &flagOverrides{}
+	visitFields(&Config{}, func(f reflect.StructField, v reflect.Value) {
+		name := f.Tag.Get("flag")
+		if name == "" || f.Tag.Get("secret") == "true" {
+			return
+		}
+		fs.Var(&fieldFlag{
+			overrides: o,
+			field:     f,
+			name:      name,
+			isBool:    v.Kind() == reflect.Bool,
+		}, name, f.Tag.Get("usage"))
+	})
+	return o
+}
+
+// visitFields calls fn for each top-level field of cfg.
+func visitFields(cfg *Config, fn func(reflect.StructField, reflect.Value)) {
+	rv := reflect.ValueOf(cfg).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		fn(rt.Field(i), rv.Field(i))
+	}
+}
+
+func setField(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(strings.Trim(raw, "\"'"))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Redacted returns a copy of c with secret fields masked, suitable for
+// printing or logging.
+func (c *Config) Redacted() *Config {
+	cp := *c
+	visitFields(&cp, func(f reflect.StructField, v reflect.Value) {
+		if f.Tag.Get("secret") == "true" && v.Kind() == reflect.String && v.String() != "" {
+			v.SetString(redacted)
+		}
+	})
+	return &cp
+}
+
+// WriteYAML writes the redacted configuration to w.
+func (c *Config) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Template describes how a new member is provisioned. Callers pick one by
+// name with "template" in the POST /v1/member body.
+type Template struct {
+	// ObjectClasses replaces the default top, person, organizationalPerson,
+	// user list.
+	ObjectClasses []string `yaml:"object_classes,omitempty"`
+	// Defaults are static attribute values, used unless the request sets
+	// the same attribute.
+	Defaults map[string][]string `yaml:"defaults,omitempty"`
+	// Computed are text/template expressions over the request, e.g.
+	// displayName: "{{.GivenName}} {{.Surname}}". They are used unless the
+	// request sets the attribute, and override Defaults. Empty results are
+	// skipped.
+	Computed map[string]string `yaml:"computed,omitempty"`
+	// OU is the container for members that do not specify one.
+	OU string `yaml:"ou,omitempty"`
+	// UserAccountControl is written after creation instead of 512 (normal,
+	// enabled account).
+	UserAccountControl int `yaml:"user_account_control,omitempty"`
+	// Groups are the DNs of groups the member is added to after creation.
+	Groups []string `yaml:"groups,omitempty"`
+}
+
+var templateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func validateTemplates(templates map[string]Template, defaultTemplate string, verr *ValidationError) {
+	for _, name := range sortedKeys(templates) {
+		t := templates[name]
+		field := "templates[" + name + "]"
+		if !templateName.MatchString(name) {
+			verr.add(field, "name must be lowercase letters, digits, _ and -")
+		}
+		for _, oc := range t.ObjectClasses {
+			if !attributeName.MatchString(oc) {
+				verr.add(field, "object class %q is not a valid name", oc)
+			}
+		}
+		for _, attr := range sortedKeys(t.Defaults) {
+			validateTemplateAttribute(field, attr, verr)
+		}
+		for _, attr := range sortedKeys(t.Computed) {
+			validateTemplateAttribute(field, attr, verr)
+			if _, err := template.New(attr).Parse(t.Computed[attr]); err != nil {
+				verr.add(field, "computed %s: %v", attr, err)
+			}
+		}
+		if t.OU != "" {
+			if _, err := ldap.ParseDN(t.OU); err != nil {
+				verr.add(field, "ou is not a valid DN: %v", err)
+			}
+		}
+		if t.UserAccountControl < 0 {
+			verr.add(field, "user_account_control must not be negative")
+		}
+		for _, g := range t.Groups {
+			if _, err := ldap.ParseDN(g); err != nil || g == "" {
+				verr.add(field, "group %q is not a valid DN", g)
+			}
+		}
+	}
+
+	if defaultTemplate != "" {
+		if _, ok := templates[defaultTemplate]; !ok {
+			verr.add("default_template", "template %q is not defined", defaultTemplate)
+		}
+	}
+}
+
+func validateTemplateAttribute(field, attr string, verr *ValidationError) {
+	switch {
+	case !attributeName.MatchString(attr):
+		verr.add(field, "attribute %q is not a valid LDAP attribute name", attr)
+	case reservedAttributes[strings.ToLower(attr)]:
+		verr.add(field, "attribute %s is managed by goberus and cannot be set", attr)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
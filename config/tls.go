@@ -0,0 +1,99 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TLSVersion parses "1.2" or "1.3". An empty name returns 0, letting
+// crypto/tls pick its default.
+func TLSVersion(name string) (uint16, error) {
+	switch name {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q (want 1.2 or 1.3)", name)
+	}
+}
+
+// CipherSuites resolves IANA cipher suite names such as
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Insecure suites are rejected. The
+// result is nil for no names, keeping the crypto/tls defaults.
+func CipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SPKIPins decodes base64 SHA-256 hashes of certificates' Subject Public
+// Key Info, as printed by
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// An optional "sha256/" prefix is accepted.
+func SPKIPins(pins []string) ([][sha256.Size]byte, error) {
+	out := make([][sha256.Size]byte, 0, len(pins))
+	for _, pin := range pins {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("invalid SPKI pin %q: want base64 of a SHA-256 hash", pin)
+		}
+		var sum [sha256.Size]byte
+		copy(sum[:], raw)
+		out = append(out, sum)
+	}
+	return out, nil
+}
+
+// VerifySPKIPins returns a tls.Config.VerifyConnection callback that accepts
+// the connection only if a trusted certificate matches one of pins. When
+// the chain was verified against a CA, any certificate of a verified chain
+// counts; otherwise only the leaf does, as its key is the only one the
+// handshake proves the peer holds and anyone can append other certificates.
+func VerifySPKIPins(pins [][sha256.Size]byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				if matchesPin(cert, pins) {
+					return nil
+				}
+			}
+		}
+		if len(cs.VerifiedChains) == 0 && len(cs.PeerCertificates) > 0 && matchesPin(cs.PeerCertificates[0], pins) {
+			return nil
+		}
+		return errors.New("server certificate does not match any pinned public key")
+	}
+}
+
+func matchesPin(cert *x509.Certificate, pins [][sha256.Size]byte) bool {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if sum == pin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package config_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+func TestTLSVersion(t *testing.T) {
+	is := is.New(t)
+	v, err := config.TLSVersion("1.3")
+	is.NoErr(err)
+	is.Equal(v, uint16(tls.VersionTLS13))
+	_, err = config.TLSVersion("1.0")
+	is.True(err != nil)
+}
+
+func TestCipherSuites(t *testing.T) {
+	is := is.New(t)
+	ids, err := config.CipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	is.NoErr(err)
+	is.Equal(ids, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})
+
+	_, err = config.CipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	is.True(err != nil) // insecure suites are not accepted
+}
+
+func TestSPKIPins(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(spki)
+	cert := &x509.Certificate{RawSubjectPublicKeyInfo: spki}
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	t.Run("matching pin is accepted", func(t *testing.T) {
+		is := is.New(t)
+		pins, err := config.SPKIPins([]string{"sha256/" + base64.StdEncoding.EncodeToString(sum[:])})
+		is.NoErr(err)
+		is.NoErr(config.VerifySPKIPins(pins)(state))
+	})
+
+	t.Run("other pin is rejected", func(t *testing.T) {
+		is := is.New(t)
+		other := sha256.Sum256([]byte("other"))
+		pins, err := config.SPKIPins([]string{base64.StdEncoding.EncodeToString(other[:])})
+		is.NoErr(err)
+		is.True(config.VerifySPKIPins(pins)(state) != nil)
+	})
+
+	t.Run("unverified pinned certificate after the leaf is rejected", func(t *testing.T) {
+		is := is.New(t)
+		pins, err := config.SPKIPins([]string{base64.StdEncoding.EncodeToString(sum[:])})
+		is.NoErr(err)
+		attacker := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("attacker key")}
+		spoofed := tls.ConnectionState{PeerCertificates: []*x509.Certificate{attacker, cert}}
+		is.True(config.VerifySPKIPins(pins)(spoofed) != nil)
+
+		// Once verified against a CA, a pinned intermediate or root counts.
+		verified := tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{attacker},
+			VerifiedChains:   [][]*x509.Certificate{{attacker, cert}},
+		}
+		is.NoErr(config.VerifySPKIPins(pins)(verified))
+	})
+
+	t.Run("malformed pin", func(t *testing.T) {
+		is := is.New(t)
+		_, err := config.SPKIPins([]string{"not-base64!"})
+		is.True(err != nil)
+	})
+}
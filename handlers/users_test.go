@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -37,6 +38,37 @@ func TestSanitizeUser(t *testing.T) {
 
 		err := SanitizeUser(&ldaps.UserInfo{Username: "!"})
 		is.True(err != nil)
-		is.True(strings.Contains(err.Error(), "username must be 2-64 characters"))
+		is.True(strings.Contains(err.Error(), "username: must contain only letters"))
+	})
+}
+
+func TestSanitizeUserReportsEveryViolation(t *testing.T) {
+	is := is.New(t)
+	err := SanitizeUser(&ldaps.UserInfo{Username: "a-very-long-user-name.", Mail: "not an address"})
+
+	var verr *ldaps.ValidationError
+	is.True(errors.As(err, &verr))
+	is.Equal(len(verr.Violations), 3)
+	is.True(strings.Contains(verr.Violations[0].Reason, "at most 20 characters"))
+	is.True(strings.Contains(verr.Violations[1].Reason, "must not end with a period"))
+	is.Equal(verr.Violations[2].Field, "mail")
+}
+
+func TestSanitizeMove(t *testing.T) {
+	t.Run("trims and lowercases names", func(t *testing.T) {
+		is := is.New(t)
+		m := &ldaps.MoveRequest{Username: " JDoe ", NewUsername: " JSmith ", OrganizationalUnit: " OU=Alumni "}
+		is.NoErr(SanitizeMove(m))
+		is.Equal(*m, ldaps.MoveRequest{Username: "jdoe", NewUsername: "jsmith", OrganizationalUnit: "OU=Alumni"})
+	})
+
+	t.Run("reports every violation", func(t *testing.T) {
+		is := is.New(t)
+		err := SanitizeMove(&ldaps.MoveRequest{NewUsername: "j smith"})
+		var verr *ldaps.ValidationError
+		is.True(errors.As(err, &verr))
+		is.Equal(len(verr.Violations), 2)
+		is.Equal(verr.Violations[0].Field, "username")
+		is.Equal(verr.Violations[1].Field, "newUsername")
 	})
 }
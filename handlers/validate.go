@@ -2,15 +2,15 @@ package handlers
 
 import (
 	"fmt"
-	"regexp"
+	"net/mail"
 	"strings"
 
 	"github.com/lugatuic/goberus/ldaps"
 )
 
-var validUser = regexp.MustCompile(`^[A-Za-z0-9@._-]{2,64}$`)
-
-// SanitizeUser trims fields and validates username.
+// SanitizeUser trims fields and validates them against the rules Active
+// Directory applies to new accounts. Violations are returned together as an
+// *ldaps.ValidationError.
 func SanitizeUser(u *ldaps.UserInfo) error {
 	if u == nil {
 		return fmt.Errorf("nil user")
@@ -24,18 +24,63 @@ func SanitizeUser(u *ldaps.UserInfo) error {
 	u.Phone = strings.TrimSpace(u.Phone)
 	u.Description = strings.TrimSpace(u.Description)
 	u.OrganizationalUnit = strings.TrimSpace(u.OrganizationalUnit)
-	u.CustomAttrs.Major = strings.TrimSpace(u.CustomAttrs.Major)
-	u.CustomAttrs.College = strings.TrimSpace(u.CustomAttrs.College)
+	u.Template = strings.TrimSpace(u.Template)
+	u.UPNSuffix = strings.TrimSpace(u.UPNSuffix)
+	for k, v := range u.CustomAttrs {
+		if s, ok := v.(string); ok {
+			u.CustomAttrs[k] = strings.TrimSpace(s)
+		}
+	}
 
+	verr := &ldaps.ValidationError{}
 	if u.Username == "" {
-		return fmt.Errorf("username is required")
+		verr.Add("username", "is required")
+	} else {
+		ldaps.ValidateUsername(u.Username, "username", verr)
 	}
-
-	if !validUser.MatchString(u.Username) {
-		return fmt.Errorf("username must be 2-64 characters and contain only letters, numbers, @, ., _, or -")
+	if u.GeneratePassword && u.Password != "" {
+		verr.Add("generatePassword", "cannot be combined with password")
+	}
+	if u.Mail != "" {
+		if addr, err := mail.ParseAddress(u.Mail); err != nil || addr.Address != u.Mail {
+			verr.Add("mail", "must be a plain email address")
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return err
 	}
 
 	u.Username = strings.ToLower(u.Username)
-	u.OrganizationalUnit = strings.ToLower(u.OrganizationalUnit)
+	return nil
+}
+
+// SanitizeMove trims a rename or move request and validates the names with
+// the rules SanitizeUser applies.
+func SanitizeMove(m *ldaps.MoveRequest) error {
+	if m == nil {
+		return fmt.Errorf("nil request")
+	}
+	m.Username = strings.TrimSpace(m.Username)
+	m.NewUsername = strings.TrimSpace(m.NewUsername)
+	m.OrganizationalUnit = strings.TrimSpace(m.OrganizationalUnit)
+	m.DisplayName = strings.TrimSpace(m.DisplayName)
+	m.UPNSuffix = strings.TrimSpace(m.UPNSuffix)
+
+	verr := &ldaps.ValidationError{}
+	if m.Username == "" {
+		verr.Add("username", "is required")
+	}
+	if m.NewUsername != "" {
+		ldaps.ValidateUsername(m.NewUsername, "newUsername", verr)
+	}
+	if m.NewUsername == "" && m.OrganizationalUnit == "" && m.DisplayName == "" && m.UPNSuffix == "" {
+		verr.Add("newUsername", "or ou, displayName or upnSuffix is required")
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	m.Username = strings.ToLower(m.Username)
+	m.NewUsername = strings.ToLower(m.NewUsername)
 	return nil
 }
@@ -0,0 +1,98 @@
+// Package admin serves operational endpoints (runtime log level, pprof,
+// build information and directory reports) meant for a separate,
+// localhost-bound listener.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/ldaps"
+)
+
+// DuplicateReporter lists values of unique attributes that several
+// accounts share.
+type DuplicateReporter interface {
+	Duplicates(ctx context.Context) ([]ldaps.Duplicate, error)
+}
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit,omitempty"`
+	GoVersion   string `json:"goVersion"`
+	VCSRevision string `json:"vcsRevision,omitempty"`
+	VCSTime     string `json:"vcsTime,omitempty"`
+	VCSModified bool   `json:"vcsModified,omitempty"`
+}
+
+// ReadBuildInfo combines the ldflags-injected version and commit with the
+// VCS metadata the Go toolchain embeds in the binary.
+func ReadBuildInfo(version, commit string) BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.VCSRevision = s.Value
+		case "vcs.time":
+			info.VCSTime = s.Value
+		case "vcs.modified":
+			info.VCSModified = s.Value == "true"
+		}
+	}
+	return info
+}
+
+// Handler returns the admin route tree:
+//
+//	GET/PUT /loglevel      zap.AtomicLevel ({"level":"debug"})
+//	GET     /buildinfo     BuildInfo as JSON
+//	GET     /duplicates    accounts sharing a unique value, when dups is set
+//	        /debug/pprof/  net/http/pprof
+func Handler(level zap.AtomicLevel, info BuildInfo, dups DuplicateReporter) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/loglevel", level)
+	mux.HandleFunc("/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(info)
+	})
+	if dups != nil {
+		mux.HandleFunc("/duplicates", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			report, err := dups.Duplicates(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_ = json.NewEncoder(w).Encode(report)
+		})
+	}
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
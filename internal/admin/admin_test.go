@@ -0,0 +1,75 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/lugatuic/goberus/internal/admin"
+	"github.com/lugatuic/goberus/ldaps"
+)
+
+type fakeReporter []ldaps.Duplicate
+
+func (f fakeReporter) Duplicates(context.Context) ([]ldaps.Duplicate, error) { return f, nil }
+
+func TestHandler(t *testing.T) {
+	t.Run("buildinfo reports version and Go runtime", func(t *testing.T) {
+		is := is.New(t)
+		handler := admin.Handler(zap.NewAtomicLevel(), admin.ReadBuildInfo("1.2.3", "abc123"), nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/buildinfo", nil))
+
+		is.Equal(rr.Code, http.StatusOK)
+		var got admin.BuildInfo
+		is.NoErr(json.Unmarshal(rr.Body.Bytes(), &got))
+		is.Equal(got.Version, "1.2.3")
+		is.Equal(got.Commit, "abc123")
+		is.Equal(got.GoVersion, runtime.Version())
+	})
+
+	t.Run("loglevel can be raised at runtime", func(t *testing.T) {
+		is := is.New(t)
+		level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		handler := admin.Handler(level, admin.BuildInfo{}, nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`)))
+
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(level.Level(), zapcore.DebugLevel)
+	})
+
+	t.Run("pprof index is served", func(t *testing.T) {
+		is := is.New(t)
+		handler := admin.Handler(zap.NewAtomicLevel(), admin.BuildInfo{}, nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+
+		is.Equal(rr.Code, http.StatusOK)
+	})
+
+	t.Run("duplicates report", func(t *testing.T) {
+		is := is.New(t)
+		dups := fakeReporter{{Attribute: "mail", Value: "jdoe@example.com", DNs: []string{"CN=jdoe,DC=example", "CN=jdoe2,DC=example"}}}
+
+		rr := httptest.NewRecorder()
+		admin.Handler(zap.NewAtomicLevel(), admin.BuildInfo{}, dups).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/duplicates", nil))
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(rr.Body.String(), `[{"attribute":"mail","value":"jdoe@example.com","dns":["CN=jdoe,DC=example","CN=jdoe2,DC=example"]}]`+"\n")
+
+		rr = httptest.NewRecorder()
+		admin.Handler(zap.NewAtomicLevel(), admin.BuildInfo{}, nil).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/duplicates", nil))
+		is.Equal(rr.Code, http.StatusNotFound)
+	})
+}
@@ -3,12 +3,15 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/internal/logctx"
+	"github.com/lugatuic/goberus/internal/metrics"
 	"github.com/lugatuic/goberus/ldaps"
 	"github.com/lugatuic/goberus/middleware"
 	"github.com/lugatuic/goberus/server"
@@ -19,44 +22,82 @@ type UserClient interface {
 	Ping(ctx context.Context) error
 	GetMemberInfo(ctx context.Context, username string) (*ldaps.MemberInfo, error)
 	AddUser(ctx context.Context, u *ldaps.UserInfo) error
+	GeneratePassword(ctx context.Context, u *ldaps.UserInfo) (string, error)
+	CheckAvailability(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error)
+	MoveUser(ctx context.Context, m *ldaps.MoveRequest) (string, error)
+	UpdateUser(ctx context.Context, r *ldaps.UpdateRequest) error
+}
+
+// certificateReporter is implemented by clients that record the directory's
+// TLS certificate, letting /readyz warn before it expires.
+type certificateReporter interface {
+	PeerCertificate() (ldaps.CertificateInfo, bool)
 }
 
 // Server composes dependencies and constructs the HTTP handler graph.
 type Server struct {
-	cfg    *config.Config
-	logger *zap.Logger
-	client UserClient
-	mux    *http.ServeMux
+	cfg     *config.Config
+	logger  *zap.Logger
+	client  UserClient
+	metrics *metrics.Metrics
+	mux     *http.ServeMux
+}
+
+// Option configures optional Server dependencies.
+type Option func(*Server)
+
+// WithMetrics enables request instrumentation and the /metrics endpoint.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
 }
 
 // New creates a Server.
-func New(cfg *config.Config, logger *zap.Logger, client UserClient) *Server {
-	return &Server{
+func New(cfg *config.Config, logger *zap.Logger, client UserClient, opts ...Option) *Server {
+	s := &Server{
 		cfg:    cfg,
 		logger: logger,
 		client: client,
 		mux:    http.NewServeMux(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Handler wires routes and middleware, returning the root handler.
 func (s *Server) Handler() http.Handler {
 	// Health endpoints
 	s.mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
-		respondJSON(s.logger, w, http.StatusOK, map[string]string{"status": "ok"})
+		respondJSON(logctx.From(r.Context(), s.logger), w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 
 	s.mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		logger := logctx.From(r.Context(), s.logger)
 		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 		defer cancel()
 		if err := s.client.Ping(ctx); err != nil {
-			s.logger.Warn("readyz.ping_failed", zap.Error(err))
-			respondJSON(s.logger, w, http.StatusServiceUnavailable, map[string]string{"status": "degraded"})
+			logger.Warn("readyz.ping_failed", zap.Error(err))
+			if s.metrics != nil {
+				s.metrics.ReadinessFailed()
+			}
+			respondJSON(logger, w, http.StatusServiceUnavailable, map[string]string{"status": "degraded"})
 			return
 		}
-		respondJSON(s.logger, w, http.StatusOK, map[string]string{"status": "ready"})
+		if warning := s.certificateWarning(); warning != "" {
+			logger.Warn("readyz.certificate_expiring", zap.String("warning", warning))
+			respondJSON(logger, w, http.StatusOK, map[string]string{"status": "warning", "warning": warning})
+			return
+		}
+		respondJSON(logger, w, http.StatusOK, map[string]string{"status": "ready"})
 	})
 
+	if s.metrics != nil {
+		s.mux.Handle("/metrics", s.metrics.Handler())
+	}
+
 	// Business routes
 	userApp := appHandler(func(w http.ResponseWriter, r *http.Request) error {
 		switch r.Method {
@@ -64,8 +105,10 @@ func (s *Server) Handler() http.Handler {
 			return server.HandleGetMember(s.client, w, r)
 		case http.MethodPost:
 			return server.HandleCreateMember(s.client, w, r)
+		case http.MethodPatch:
+			return server.HandleUpdateMember(s.client, w, r)
 		default:
-			respondJSON(s.logger, w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			respondJSON(logctx.From(r.Context(), s.logger), w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 			return nil
 		}
 	})
@@ -73,16 +116,65 @@ func (s *Server) Handler() http.Handler {
 	// Wrap business handler with error handling
 	s.mux.Handle("/v1/member", s.makeAppHandler(userApp))
 
-	// Mat-style middleware stack: Recover (outer), RequestID, Logger.
-	// Apply to entire mux so all routes get middleware
-	var handler http.Handler = s.mux
+	availabilityApp := appHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet {
+			respondJSON(logctx.From(r.Context(), s.logger), w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return nil
+		}
+		return server.HandleAvailability(s.client, w, r)
+	})
+	s.mux.Handle("/v1/member/availability", s.makeAppHandler(availabilityApp))
+
+	moveApp := appHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			respondJSON(logctx.From(r.Context(), s.logger), w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return nil
+		}
+		return server.HandleMoveMember(s.client, w, r)
+	})
+	s.mux.Handle("/v1/member/move", s.makeAppHandler(moveApp))
+
+	passwordApp := appHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			respondJSON(logctx.From(r.Context(), s.logger), w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return nil
+		}
+		return server.HandleGeneratePassword(s.client, w, r)
+	})
+	s.mux.Handle("/v1/password", s.makeAppHandler(passwordApp))
+
+	// Mat-style middleware stack: Recover (outer), RequestID, Tracing,
+	// ClientCertIdentity, Logger, Metrics.
+	// Apply to entire mux so all routes get middleware. Metrics and TraceRoute sit
+	// innermost so they observe the route pattern the mux records on the request.
+	var handler http.Handler = middleware.TraceRoute(s.mux)
+	if s.metrics != nil {
+		handler = middleware.Metrics(s.metrics, handler)
+	}
 	handler = middleware.Logger(s.logger, handler)
+	handler = middleware.ClientCertIdentity(handler)
+	handler = middleware.Tracing(handler)   // continues W3C traceparent, tags X-Request-ID
 	handler = middleware.RequestID(handler) // adds X-Request-ID if missing
 	handler = middleware.Recover(s.logger, handler)
 
 	return handler
 }
 
+// certificateWarning describes a directory certificate expiring within the
+// configured window, or returns "". The service stays ready: binds keep
+// working until the certificate actually expires.
+func (s *Server) certificateWarning() string {
+	reporter, ok := s.client.(certificateReporter)
+	if !ok || s.cfg == nil || s.cfg.CertExpiryWarning <= 0 {
+		return ""
+	}
+	cert, ok := reporter.PeerCertificate()
+	if !ok || time.Until(cert.NotAfter) > s.cfg.CertExpiryWarning {
+		return ""
+	}
+	return fmt.Sprintf("LDAP server certificate %q expires %s", cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339))
+}
+
 // appHandler is an application handler that returns an error.
 // Errors are logged and translated to HTTP responses by the adapter.
 type appHandler func(http.ResponseWriter, *http.Request) error
@@ -91,15 +183,17 @@ type appHandler func(http.ResponseWriter, *http.Request) error
 func (s *Server) makeAppHandler(fn appHandler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
-			// Log internal error with context
-			s.logger.Error("handler.error", zap.Error(err), zap.String("path", r.URL.Path), zap.String("method", r.Method))
+			// Log internal error with the request-scoped logger (request ID, method, path).
+			logger := logctx.From(r.Context(), s.logger)
+			logger.Error("handler.error", zap.Error(err))
 			// Return a generic 500 with JSON (do not leak internal details).
-			respondJSON(s.logger, w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			respondJSON(logger, w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
 		}
 	})
 }
 
-// respondJSON writes a JSON response with proper headers.
+// respondJSON writes a JSON response with proper headers. Callers pass the
+// request-scoped logger from logctx so encode errors stay correlated.
 func respondJSON(logger *zap.Logger, w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -8,12 +8,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/matryer/is"
 	"go.uber.org/zap"
 
 	"github.com/lugatuic/goberus/config"
 	"github.com/lugatuic/goberus/internal/httpserver"
+	"github.com/lugatuic/goberus/internal/metrics"
 	"github.com/lugatuic/goberus/ldaps"
 )
 
@@ -23,6 +25,22 @@ type fakeClient struct {
 	addUser       func(ctx context.Context, u *ldaps.UserInfo) error
 }
 
+func (f *fakeClient) GeneratePassword(ctx context.Context, u *ldaps.UserInfo) (string, error) {
+	return "Generated-Passw0rd", nil
+}
+
+func (f *fakeClient) CheckAvailability(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error) {
+	return &ldaps.Availability{Username: req.Username, Available: true}, nil
+}
+
+func (f *fakeClient) MoveUser(ctx context.Context, m *ldaps.MoveRequest) (string, error) {
+	return "CN=" + m.NewUsername + ",OU=Alumni,DC=example,DC=local", nil
+}
+
+func (f *fakeClient) UpdateUser(ctx context.Context, r *ldaps.UpdateRequest) error {
+	return nil
+}
+
 func (f *fakeClient) Ping(ctx context.Context) error {
 	return f.pingErr
 }
@@ -104,6 +122,36 @@ func TestHealthEndpoints(t *testing.T) {
 		is.NoErr(json.Unmarshal(rr.Body.Bytes(), &resp))
 		is.Equal(resp["status"], "degraded")
 	})
+
+	t.Run("/readyz warns when the LDAP certificate expires soon", func(t *testing.T) {
+		is := is.New(t)
+		cfg := &config.Config{BindAddr: ":8080", CertExpiryWarning: 14 * 24 * time.Hour}
+		client := &certClient{cert: ldaps.CertificateInfo{
+			Subject:  "CN=dc01.example.local",
+			NotAfter: time.Now().Add(3 * 24 * time.Hour),
+		}}
+
+		handler := httpserver.New(cfg, zap.NewNop(), client).Handler()
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(rr.Code, http.StatusOK) // still ready; binds work until expiry
+		var resp map[string]string
+		is.NoErr(json.Unmarshal(rr.Body.Bytes(), &resp))
+		is.Equal(resp["status"], "warning")
+		is.True(strings.Contains(resp["warning"], "CN=dc01.example.local"))
+	})
+}
+
+// certClient is a fakeClient that reports a directory certificate.
+type certClient struct {
+	fakeClient
+	cert ldaps.CertificateInfo
+}
+
+func (c *certClient) PeerCertificate() (ldaps.CertificateInfo, bool) {
+	return c.cert, true
 }
 
 func TestBusinessRoutes(t *testing.T) {
@@ -178,6 +226,52 @@ func TestBusinessRoutes(t *testing.T) {
 		is.True(rr.Header().Get("X-Request-ID") != "")
 	})
 
+	t.Run("/v1/member/availability GET", func(t *testing.T) {
+		is := is.New(t)
+		s := httpserver.New(&config.Config{BindAddr: ":8080"}, zap.NewNop(), &fakeClient{})
+		handler := s.Handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/member/availability?username=jdoe", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(rr.Body.String(), "{\"username\":\"jdoe\",\"available\":true}\n")
+
+		req = httptest.NewRequest(http.MethodPost, "/v1/member/availability", nil)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(rr.Code, http.StatusMethodNotAllowed)
+	})
+
+	t.Run("/v1/member/move POST", func(t *testing.T) {
+		is := is.New(t)
+		s := httpserver.New(&config.Config{BindAddr: ":8080"}, zap.NewNop(), &fakeClient{})
+		handler := s.Handler()
+
+		body := strings.NewReader(`{"username":"jdoe","newUsername":"jsmith","ou":"OU=Alumni"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member/move", body)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(rr.Body.String(), "{\"dn\":\"CN=jsmith,OU=Alumni,DC=example,DC=local\",\"status\":\"moved\"}\n")
+	})
+
+	t.Run("/v1/member PATCH", func(t *testing.T) {
+		is := is.New(t)
+		s := httpserver.New(&config.Config{BindAddr: ":8080"}, zap.NewNop(), &fakeClient{})
+		handler := s.Handler()
+
+		body := strings.NewReader(`{"username":"jdoe","custom":{"major":"CS"}}`)
+		req := httptest.NewRequest(http.MethodPatch, "/v1/member", body)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(rr.Body.String(), "{\"status\":\"updated\"}\n")
+	})
+
 	t.Run("/v1/member unsupported method returns JSON error", func(t *testing.T) {
 		is := is.New(t)
 		logger := zap.NewNop()
@@ -217,3 +311,28 @@ func TestRequestIDPreservation(t *testing.T) {
 
 	is.Equal(rr.Header().Get("X-Request-ID"), existingID)
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	is := is.New(t)
+	logger := zap.NewNop()
+	cfg := &config.Config{BindAddr: ":8080"}
+	client := &fakeClient{pingErr: errors.New("connection failed")}
+
+	s := httpserver.New(cfg, logger, client, httpserver.WithMetrics(metrics.New()))
+	handler := s.Handler()
+
+	for _, path := range []string{"/livez", "/readyz", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	is.Equal(rr.Code, http.StatusOK)
+	body := rr.Body.String()
+	is.True(strings.Contains(body, `goberus_http_requests_total{method="GET",route="/livez",status="200"} 1`))
+	is.True(strings.Contains(body, `goberus_http_requests_total{method="GET",route="/readyz",status="503"} 1`))
+	is.True(strings.Contains(body, `goberus_http_requests_total{method="GET",route="unmatched",status="404"} 1`))
+	is.True(strings.Contains(body, "goberus_readiness_failures_total 1"))
+}
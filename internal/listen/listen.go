@@ -0,0 +1,103 @@
+// Package listen opens the HTTP listener: a socket passed in by systemd
+// socket activation, a unix domain socket ("unix:/path"), or TCP.
+package listen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UnixPrefix marks a bind address as a unix domain socket path.
+const UnixPrefix = "unix:"
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// Listen returns the listener for addr. When systemd passed sockets to this
+// process they take precedence and addr is ignored, so the socket outlives
+// restarts. A unix socket is created with mode, replacing a stale socket
+// file left by an unclean exit; it is removed again when the listener
+// closes. The second result names how the listener was obtained.
+func Listen(addr string, mode fs.FileMode) (net.Listener, string, error) {
+	lns, err := systemdListeners()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(lns) > 0 {
+		for _, extra := range lns[1:] {
+			_ = extra.Close()
+		}
+		return lns[0], "systemd", nil
+	}
+
+	path, ok := strings.CutPrefix(addr, UnixPrefix)
+	if !ok {
+		ln, err := net.Listen("tcp", addr)
+		return ln, "tcp", err
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, "", err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, "", fmt.Errorf("set socket permissions: %w", err)
+	}
+	return ln, "unix", nil
+}
+
+// systemdListeners returns the sockets passed via LISTEN_FDS, if they are
+// addressed to this process, and clears the variables so child processes do
+// not inherit them.
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(key)
+	}
+
+	lns := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		_ = f.Close() // FileListener holds its own duplicate
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("systemd socket %d: %w", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// removeStaleSocket deletes path if it is a socket. Any other file is left
+// alone so a typo cannot delete data.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,69 @@
+package listen_test
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/internal/listen"
+)
+
+func TestListenUnix(t *testing.T) {
+	t.Run("creates the socket with the requested mode", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "goberus.sock")
+
+		ln, kind, err := listen.Listen(listen.UnixPrefix+path, 0o660)
+		is.NoErr(err)
+		is.Equal(kind, "unix")
+
+		fi, err := os.Stat(path)
+		is.NoErr(err)
+		is.Equal(fi.Mode().Perm(), fs.FileMode(0o660))
+
+		conn, err := net.Dial("unix", path)
+		is.NoErr(err)
+		_ = conn.Close()
+
+		is.NoErr(ln.Close())
+		_, err = os.Stat(path)
+		is.True(os.IsNotExist(err)) // removed on close
+	})
+
+	t.Run("replaces a stale socket", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "goberus.sock")
+		stale, err := net.Listen("unix", path)
+		is.NoErr(err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		is.NoErr(stale.Close())
+
+		ln, _, err := listen.Listen(listen.UnixPrefix+path, 0o600)
+		is.NoErr(err)
+		is.NoErr(ln.Close())
+	})
+
+	t.Run("refuses to replace a regular file", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "data")
+		is.NoErr(os.WriteFile(path, []byte("keep"), 0o600))
+
+		_, _, err := listen.Listen(listen.UnixPrefix+path, 0o600)
+		is.True(err != nil)
+	})
+}
+
+func TestListenIgnoresForeignSystemdSockets(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	ln, kind, err := listen.Listen("127.0.0.1:0", 0)
+	is.NoErr(err)
+	is.Equal(kind, "tcp")
+	is.NoErr(ln.Close())
+}
@@ -0,0 +1,49 @@
+// Package logctx carries a request-scoped zap logger through a context so
+// that every layer, down to the LDAP client, logs with the same correlation
+// fields (request ID, method, path, actor).
+package logctx
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+type actorKey struct{}
+
+// With returns a copy of ctx carrying l.
+func With(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// From returns the logger carried by ctx, falling back to fallback and then
+// to a no-op logger so callers never need a nil check.
+func From(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok && l != nil {
+			return l
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return zap.NewNop()
+}
+
+// WithActor records the authenticated caller on ctx and adds it to the
+// request-scoped logger, if any.
+func WithActor(ctx context.Context, actor string) context.Context {
+	ctx = context.WithValue(ctx, actorKey{}, actor)
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok && l != nil {
+		ctx = With(ctx, l.With(zap.String("actor", actor)))
+	}
+	return ctx
+}
+
+// Actor returns the authenticated caller recorded on ctx, or "".
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
@@ -0,0 +1,54 @@
+package logctx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/lugatuic/goberus/internal/logctx"
+)
+
+func TestFrom(t *testing.T) {
+	t.Run("falls back when context has no logger", func(t *testing.T) {
+		is := is.New(t)
+		fallback := zap.NewExample()
+		is.Equal(logctx.From(context.Background(), fallback), fallback)
+		is.True(logctx.From(context.Background(), nil) != nil)
+	})
+
+	t.Run("actor is added to the request logger", func(t *testing.T) {
+		is := is.New(t)
+		core, logs := observer.New(zapcore.InfoLevel)
+		ctx := logctx.With(context.Background(), zap.New(core).With(zap.String("request_id", "abc")))
+		ctx = logctx.WithActor(ctx, "officer")
+
+		logctx.From(ctx, nil).Info("hello")
+
+		is.Equal(logctx.Actor(ctx), "officer")
+		fields := logs.All()[0].ContextMap()
+		is.Equal(fields["request_id"], "abc")
+		is.Equal(fields["actor"], "officer")
+	})
+}
+
+func TestRedactCore(t *testing.T) {
+	is := is.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core, zap.WrapCore(logctx.RedactCore)).With(zap.String("bind_password", "hunter2"))
+
+	logger.Info("bind",
+		zap.String("Password", "s3cret"),
+		zap.String("authorization", "Bearer abc"),
+		zap.String("username", "jdoe"),
+	)
+
+	fields := logs.All()[0].ContextMap()
+	is.Equal(fields["bind_password"], logctx.Redacted)
+	is.Equal(fields["Password"], logctx.Redacted)
+	is.Equal(fields["authorization"], logctx.Redacted)
+	is.Equal(fields["username"], "jdoe")
+}
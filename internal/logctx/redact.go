@@ -0,0 +1,73 @@
+package logctx
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the value of any sensitive field.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys lists field names, compared case-insensitively, whose values
+// must never reach the logs. Any key containing "password" is also redacted.
+var sensitiveKeys = map[string]struct{}{
+	"authorization": {},
+	"cookie":        {},
+	"secret":        {},
+	"token":         {},
+	"unicodepwd":    {},
+}
+
+// RedactCore wraps core so sensitive fields are logged as Redacted no matter
+// which layer added them. Install it with zap.WrapCore.
+func RedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+type redactCore struct {
+	zapcore.Core
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redact(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redact(fields))
+}
+
+func redact(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		if !isSensitive(f.Key) {
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = zap.String(f.Key, Redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func isSensitive(key string) bool {
+	k := strings.ToLower(key)
+	if strings.Contains(k, "password") {
+		return true
+	}
+	_, ok := sensitiveKeys[k]
+	return ok
+}
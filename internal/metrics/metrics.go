@@ -0,0 +1,138 @@
+// Package metrics exposes Goberus' Prometheus collectors. It implements the
+// observer interfaces declared by the middleware and ldaps packages so those
+// packages stay free of any Prometheus dependency.
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goberus"
+
+// Metrics owns a dedicated registry and the collectors registered on it.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	ldapOperations *prometheus.CounterVec
+	ldapDuration   *prometheus.HistogramVec
+	ldapConns      prometheus.Gauge
+	// certNotAfter is the directory certificate expiry in Unix seconds, or
+	// 0 before the first TLS handshake.
+	certNotAfter atomic.Int64
+
+	readinessFailures prometheus.Counter
+}
+
+// New creates a Metrics with all collectors registered.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		ldapOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ldap",
+			Name:      "operations_total",
+			Help:      "LDAP operations, by operation and LDAP result code.",
+		}, []string{"op", "result"}),
+		ldapDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "ldap",
+			Name:      "operation_duration_seconds",
+			Help:      "LDAP operation latency, by operation and LDAP result code.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 15},
+		}, []string{"op", "result"}),
+		ldapConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "ldap",
+			Name:      "connections_open",
+			Help:      "LDAP connections currently open.",
+		}),
+		readinessFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "readiness",
+			Name:      "failures_total",
+			Help:      "Readiness probes that reported the service as degraded.",
+		}),
+	}
+
+	certExpiry := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ldap",
+		Name:      "certificate_expiry_days",
+		Help:      "Days until the earliest-expiring certificate presented by the directory expires (NaN before the first TLS handshake).",
+	}, m.certExpiryDays)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.ldapOperations,
+		m.ldapDuration,
+		m.ldapConns,
+		certExpiry,
+		m.readinessFailures,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveHTTP implements middleware.HTTPObserver.
+func (m *Metrics) ObserveHTTP(route, method string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// OperationDone implements ldaps.Hooks.
+func (m *Metrics) OperationDone(op, result string, d time.Duration) {
+	m.ldapOperations.WithLabelValues(op, result).Inc()
+	m.ldapDuration.WithLabelValues(op, result).Observe(d.Seconds())
+}
+
+// ConnOpened implements ldaps.Hooks.
+func (m *Metrics) ConnOpened() { m.ldapConns.Inc() }
+
+// ConnClosed implements ldaps.Hooks.
+func (m *Metrics) ConnClosed() { m.ldapConns.Dec() }
+
+// CertificateSeen implements ldaps.Hooks.
+func (m *Metrics) CertificateSeen(notAfter time.Time) { m.certNotAfter.Store(notAfter.Unix()) }
+
+func (m *Metrics) certExpiryDays() float64 {
+	notAfter := m.certNotAfter.Load()
+	if notAfter == 0 {
+		return math.NaN()
+	}
+	return time.Until(time.Unix(notAfter, 0)).Hours() / 24
+}
+
+// ReadinessFailed records a failed readiness probe.
+func (m *Metrics) ReadinessFailed() { m.readinessFailures.Inc() }
@@ -0,0 +1,123 @@
+// Package passwords generates and screens passwords.
+package passwords
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Character classes used by Generate. Visually ambiguous characters are
+// kept because the service password is only ever read by machines.
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+// Character classes for member passwords, which people read and type: the
+// classes above without l, I, O, 0, 1 and |.
+const (
+	readableLower  = "abcdefghijkmnopqrstuvwxyz"
+	readableUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	readableDigit  = "23456789"
+	readableSymbol = "!#$%&()*+,-./:;<=>?@[]^_{}~"
+)
+
+// Classes maps the character class names accepted by ParseClasses to
+// their characters.
+var Classes = map[string]string{
+	"lower":  readableLower,
+	"upper":  readableUpper,
+	"digit":  readableDigit,
+	"symbol": readableSymbol,
+}
+
+// ParseClasses parses a comma-separated list of class names. An empty list
+// selects every class.
+func ParseClasses(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return []string{readableLower, readableUpper, readableDigit, readableSymbol}, nil
+	}
+	var classes []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		chars, ok := Classes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown character class %q (want lower, upper, digit or symbol)", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			classes = append(classes, chars)
+		}
+	}
+	return classes, nil
+}
+
+// Generate returns a random password of the given length drawn from
+// crypto/rand that contains at least one lowercase letter, uppercase letter,
+// digit and symbol, satisfying AD's default complexity rules.
+func Generate(length int) (string, error) {
+	return generate(length, []string{lowerChars, upperChars, digitChars, symbolChars})
+}
+
+// generate returns a random password with at least one character of each
+// class.
+func generate(length int, classes []string) (string, error) {
+	if length < len(classes) {
+		return "", fmt.Errorf("password length %d is shorter than the %d required character classes", length, len(classes))
+	}
+	all := strings.Join(classes, "")
+
+	out := make([]byte, 0, length)
+	for _, class := range classes {
+		ch, err := pick(class)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+	for len(out) < length {
+		ch, err := pick(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+	if err := shuffle(out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func pick(chars string) (byte, error) {
+	n, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n], nil
+}
+
+// shuffle is a Fisher–Yates shuffle so the guaranteed characters do not
+// always lead the password.
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("read random: %w", err)
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,29 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("contains every character class", func(t *testing.T) {
+		is := is.New(t)
+		for i := 0; i < 50; i++ {
+			pw, err := Generate(8)
+			is.NoErr(err)
+			is.Equal(len(pw), 8)
+			is.True(strings.ContainsAny(pw, lowerChars))
+			is.True(strings.ContainsAny(pw, upperChars))
+			is.True(strings.ContainsAny(pw, digitChars))
+			is.True(strings.ContainsAny(pw, symbolChars))
+		}
+	})
+
+	t.Run("rejects lengths below the class count", func(t *testing.T) {
+		is := is.New(t)
+		_, err := Generate(3)
+		is.True(err != nil)
+	})
+}
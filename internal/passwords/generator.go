@@ -0,0 +1,105 @@
+package passwords
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Generator produces passwords for new accounts, either random characters
+// from a set of classes or a passphrase of words from a wordlist.
+type Generator struct {
+	length   int
+	classes  []string
+	wordlist []string
+	words    int
+}
+
+// minWordlist keeps passphrases from a tiny list guessable.
+const minWordlist = 1024
+
+// NewGenerator returns a Generator of passwords of at least length characters
+// drawn from classes (see ParseClasses). With a wordlist file it produces
+// passphrases of at least words words instead.
+func NewGenerator(length int, classes, wordlistPath string, words int) (*Generator, error) {
+	g := &Generator{length: length, words: words}
+	if wordlistPath != "" {
+		list, err := LoadWordlist(wordlistPath)
+		if err != nil {
+			return nil, err
+		}
+		g.wordlist = list
+		return g, nil
+	}
+	var err error
+	if g.classes, err = ParseClasses(classes); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Generate returns a new password at least minLength characters long, for
+// instance the domain's minimum password length.
+func (g *Generator) Generate(minLength int) (string, error) {
+	length := max(g.length, minLength)
+	if g.wordlist != nil {
+		return g.passphrase(length)
+	}
+	return generate(length, g.classes)
+}
+
+// passphrase joins capitalized random words with "-" and appends a digit,
+// so it has upper and lower case letters, a digit and a symbol.
+func (g *Generator) passphrase(minLength int) (string, error) {
+	var parts []string
+	length := 0
+	for len(parts) < g.words || length < minLength {
+		n, err := randInt(len(g.wordlist))
+		if err != nil {
+			return "", err
+		}
+		word := []rune(g.wordlist[n])
+		word[0] = unicode.ToUpper(word[0])
+		parts = append(parts, string(word))
+		length += len(word) + 1
+	}
+	digit, err := pick(readableDigit)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(parts, "-") + string(digit), nil
+}
+
+// LoadWordlist reads one word per line. Diceware lists ("11111<TAB>word")
+// are accepted; the last field of each line is the word.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open wordlist: %w", err)
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		w := strings.ToLower(fields[len(fields)-1])
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read wordlist: %w", err)
+	}
+	if len(words) < minWordlist {
+		return nil, fmt.Errorf("wordlist %s has %d distinct words, need at least %d", path, len(words), minWordlist)
+	}
+	return words, nil
+}
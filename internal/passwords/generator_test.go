@@ -0,0 +1,68 @@
+package passwords
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/matryer/is"
+)
+
+func TestGenerator(t *testing.T) {
+	t.Run("grows to the minimum length", func(t *testing.T) {
+		is := is.New(t)
+		g, err := NewGenerator(12, "lower,digit", "", 5)
+		is.NoErr(err)
+		pw, err := g.Generate(20)
+		is.NoErr(err)
+		is.Equal(len(pw), 20)
+		is.True(strings.ContainsAny(pw, digitChars))
+		is.True(!strings.ContainsAny(pw, upperChars+symbolChars))
+	})
+
+	t.Run("leaves out ambiguous characters", func(t *testing.T) {
+		is := is.New(t)
+		g, err := NewGenerator(200, "", "", 5)
+		is.NoErr(err)
+		pw, err := g.Generate(0)
+		is.NoErr(err)
+		is.True(!strings.ContainsAny(pw, "lIO01|"))
+	})
+
+	t.Run("rejects unknown classes", func(t *testing.T) {
+		is := is.New(t)
+		_, err := NewGenerator(12, "lower,emoji", "", 5)
+		is.True(err != nil)
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		is := is.New(t)
+		var lines []string
+		for i := 0; i < minWordlist; i++ {
+			lines = append(lines, fmt.Sprintf("%05d\tword%d", i, i))
+		}
+		path := filepath.Join(t.TempDir(), "words.txt")
+		is.NoErr(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+		g, err := NewGenerator(16, "", path, 4)
+		is.NoErr(err)
+		pw, err := g.Generate(40)
+		is.NoErr(err)
+		is.True(len(pw) >= 40)
+		words := strings.Split(pw, "-")
+		is.True(len(words) >= 4)
+		is.True(unicode.IsUpper(rune(words[0][0])))
+		is.True(unicode.IsDigit(rune(pw[len(pw)-1])))
+	})
+
+	t.Run("rejects a short wordlist", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "words.txt")
+		is.NoErr(os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o600))
+		_, err := NewGenerator(16, "", path, 4)
+		is.True(err != nil)
+	})
+}
@@ -0,0 +1,236 @@
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Screener reports whether a password appears in a breached-password corpus.
+type Screener interface {
+	Breached(password string) (bool, error)
+	Close() error
+}
+
+// filterMagic starts a Bloom filter file written by BuildFilter.
+var filterMagic = []byte("GBBF0001")
+
+const filterHeaderLen = 8 + 4 + 8 // magic, hash count, bit count
+
+// Open opens a corpus for screening: either a Bloom filter written by
+// BuildFilter, or a Have I Been Pwned SHA-1 file sorted by hash with one
+// HASH:COUNT line per entry.
+func Open(path string) (Screener, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password corpus: %w", err)
+	}
+	head := make([]byte, filterHeaderLen)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = f.Close()
+		return nil, fmt.Errorf("read breached password corpus: %w", err)
+	}
+	if n == filterHeaderLen && bytes.Equal(head[:8], filterMagic) {
+		return openFilter(f, head)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &hashFile{f: f, size: fi.Size()}, nil
+}
+
+// sha1Hex returns the key the corpus uses for password: its unsalted SHA-1
+// in uppercase hex.
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// hashFile binary-searches a sorted HIBP file in place, so corpora of many
+// gigabytes need no memory.
+type hashFile struct {
+	f    *os.File
+	size int64
+}
+
+func (h *hashFile) Breached(password string) (bool, error) {
+	want := sha1Hex(password)
+	// Lines starting before lo sort below want; lines starting at or after
+	// hi sort above it.
+	lo, hi := int64(0), h.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := h.lineAfter(mid)
+		if err != nil {
+			return false, err
+		}
+		if line == "" || start >= hi {
+			hi = mid
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		switch strings.Compare(strings.ToUpper(hash), want) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// lineAfter returns the first line starting at or after off, where a line
+// starts at offset 0 or after a newline, and its offset.
+func (h *hashFile) lineAfter(off int64) (int64, string, error) {
+	start := off
+	if off > 0 {
+		// Back up one byte: if it is a newline, off starts a line.
+		start = off - 1
+	}
+	r := bufio.NewReader(io.NewSectionReader(h.f, start, h.size-start))
+	if off > 0 {
+		skipped, err := r.ReadString('\n')
+		if err != nil {
+			return 0, "", nilIfEOF(err)
+		}
+		start += int64(len(skipped))
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, "", err
+	}
+	return start, strings.TrimRight(line, "\r\n"), nil
+}
+
+func nilIfEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (h *hashFile) Close() error { return h.f.Close() }
+
+// filter is a Bloom filter read from disk bit by bit, so it need not fit in
+// memory. False positives are possible at the rate it was built for; false
+// negatives are not.
+type filter struct {
+	f      *os.File
+	hashes uint32
+	bits   uint64
+}
+
+func openFilter(f *os.File, head []byte) (*filter, error) {
+	flt := &filter{
+		f:      f,
+		hashes: binary.BigEndian.Uint32(head[8:12]),
+		bits:   binary.BigEndian.Uint64(head[12:20]),
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if flt.hashes == 0 || flt.bits == 0 || uint64(fi.Size()-filterHeaderLen) < (flt.bits+7)/8 {
+		_ = f.Close()
+		return nil, errors.New("breached password filter is truncated or corrupt")
+	}
+	return flt, nil
+}
+
+func (flt *filter) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	var b [1]byte
+	for _, bit := range bitPositions(sum[:], flt.hashes, flt.bits) {
+		if _, err := flt.f.ReadAt(b[:], filterHeaderLen+int64(bit/8)); err != nil {
+			return false, fmt.Errorf("read breached password filter: %w", err)
+		}
+		if b[0]&(1<<(bit%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (flt *filter) Close() error { return flt.f.Close() }
+
+// bitPositions derives the filter bits for a SHA-1 digest by double hashing
+// its first two 64-bit words.
+func bitPositions(digest []byte, hashes uint32, bits uint64) []uint64 {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	out := make([]uint64, hashes)
+	for i := range out {
+		out[i] = (h1 + uint64(i)*h2) % bits
+	}
+	return out
+}
+
+// FilterSize returns the bit and hash counts of a Bloom filter holding n
+// entries with false positive rate p.
+func FilterSize(n uint64, p float64) (bits uint64, hashes uint32) {
+	if n == 0 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+	return uint64(m), uint32(k)
+}
+
+// BuildFilter reads n HIBP "HASH:COUNT" lines from r and writes a Bloom
+// filter with false positive rate p to w. Lines that are not SHA-1 hashes
+// are rejected.
+func BuildFilter(r io.Reader, n uint64, p float64, w io.Writer) error {
+	if p <= 0 || p >= 1 {
+		return fmt.Errorf("false positive rate %v must be between 0 and 1", p)
+	}
+	bits, hashes := FilterSize(n, p)
+	set := make([]byte, (bits+7)/8)
+
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		digest, err := hex.DecodeString(hash)
+		if err != nil || len(digest) != sha1.Size {
+			return fmt.Errorf("line %d: %q is not a SHA-1 hash", line, hash)
+		}
+		for _, bit := range bitPositions(digest, hashes, bits) {
+			set[bit/8] |= 1 << (bit % 8)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read corpus: %w", err)
+	}
+
+	head := make([]byte, filterHeaderLen)
+	copy(head, filterMagic)
+	binary.BigEndian.PutUint32(head[8:12], hashes)
+	binary.BigEndian.PutUint64(head[12:20], bits)
+	if _, err := w.Write(head); err != nil {
+		return err
+	}
+	_, err := w.Write(set)
+	return err
+}
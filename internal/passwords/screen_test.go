@@ -0,0 +1,121 @@
+package passwords
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+var breached = []string{"password", "123456", "letmein", "Summer2024!", "qwerty"}
+
+// writeCorpus writes breached as a sorted HIBP file.
+func writeCorpus(t *testing.T, lineEnd string) string {
+	t.Helper()
+	var lines []string
+	for _, pw := range breached {
+		lines = append(lines, sha1Hex(pw)+":42")
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, lineEnd)+lineEnd), 0o600); err != nil {
+		t.Fatalf("write corpus: %v", err)
+	}
+	return path
+}
+
+func TestHashFile(t *testing.T) {
+	for name, lineEnd := range map[string]string{"LF": "\n", "CRLF": "\r\n"} {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			s, err := Open(writeCorpus(t, lineEnd))
+			is.NoErr(err)
+			defer s.Close()
+
+			for _, pw := range breached {
+				got, err := s.Breached(pw)
+				is.NoErr(err)
+				is.True(got) // every corpus entry is found
+			}
+			for _, pw := range []string{"correct horse battery staple", "", "Password"} {
+				got, err := s.Breached(pw)
+				is.NoErr(err)
+				is.True(!got)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	is := is.New(t)
+	corpus, err := os.ReadFile(writeCorpus(t, "\n"))
+	is.NoErr(err)
+
+	var buf bytes.Buffer
+	is.NoErr(BuildFilter(bytes.NewReader(corpus), uint64(len(breached)), 0.001, &buf))
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	is.NoErr(os.WriteFile(path, buf.Bytes(), 0o600))
+
+	s, err := Open(path)
+	is.NoErr(err)
+	defer s.Close()
+	_, isFilter := s.(*filter)
+	is.True(isFilter)
+
+	for _, pw := range breached {
+		got, err := s.Breached(pw)
+		is.NoErr(err)
+		is.True(got)
+	}
+	got, err := s.Breached("correct horse battery staple")
+	is.NoErr(err)
+	is.True(!got)
+}
+
+func TestBuildFilterRejectsMalformedLines(t *testing.T) {
+	is := is.New(t)
+	err := BuildFilter(strings.NewReader("not-a-hash:1\n"), 1, 0.01, &bytes.Buffer{})
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "line 1"))
+}
+
+func TestOpenRejectsTruncatedFilter(t *testing.T) {
+	is := is.New(t)
+	var buf bytes.Buffer
+	is.NoErr(BuildFilter(strings.NewReader(sha1Hex("password")+":1\n"), 1000, 0.01, &buf))
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	is.NoErr(os.WriteFile(path, buf.Bytes()[:buf.Len()/2], 0o600))
+
+	_, err := Open(path)
+	is.True(err != nil)
+}
+
+func TestHashFileLargeCorpus(t *testing.T) {
+	is := is.New(t)
+	var lines []string
+	for i := 0; i < 2000; i++ {
+		lines = append(lines, sha1Hex(strconv.Itoa(i))+":"+strconv.Itoa(i))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	is.NoErr(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600)) // no trailing newline
+
+	s, err := Open(path)
+	is.NoErr(err)
+	defer s.Close()
+	for i := 0; i < 2000; i++ {
+		got, err := s.Breached(strconv.Itoa(i))
+		is.NoErr(err)
+		is.True(got)
+	}
+	for i := 2000; i < 2100; i++ {
+		got, err := s.Breached(strconv.Itoa(i))
+		is.NoErr(err)
+		is.True(!got)
+	}
+}
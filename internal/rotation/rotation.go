@@ -0,0 +1,204 @@
+// Package rotation periodically rotates the service bind account's password:
+// it generates a new password, stages it in the secret store, changes it in
+// the directory, commits the store, verifies a fresh bind and finally moves
+// pooled connections over. Any failed step is retried later and a rotation
+// interrupted after staging is completed or discarded on the next attempt.
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/internal/passwords"
+	"github.com/lugatuic/goberus/internal/secrets"
+)
+
+const (
+	passwordLength = 32
+	checkEvery     = time.Hour
+	retryDelay     = 5 * time.Minute
+	verifyAttempts = 3
+	verifyBackoff  = 2 * time.Second
+)
+
+// Account is the directory side of the service account.
+type Account interface {
+	BindPasswordLastSet(ctx context.Context) (time.Time, error)
+	ChangeBindPassword(ctx context.Context, oldPassword, newPassword string) error
+	VerifyBind(ctx context.Context, password string) error
+	ResetConnections() error
+}
+
+// Rotator rotates the bind password once it is older than interval.
+type Rotator struct {
+	account  Account
+	store    secrets.Store
+	interval time.Duration
+	logger   *zap.Logger
+
+	generate      func() (string, error)
+	now           func() time.Time
+	verifyBackoff time.Duration
+}
+
+// New creates a Rotator.
+func New(account Account, store secrets.Store, interval time.Duration, logger *zap.Logger) *Rotator {
+	return &Rotator{
+		account:       account,
+		store:         store,
+		interval:      interval,
+		logger:        logger,
+		generate:      func() (string, error) { return passwords.Generate(passwordLength) },
+		now:           time.Now,
+		verifyBackoff: verifyBackoff,
+	}
+}
+
+// Run checks hourly whether the password is due and rotates it, retrying
+// sooner after a failure, until ctx is cancelled.
+func (r *Rotator) Run(ctx context.Context) {
+	for {
+		delay := checkEvery
+		if err := r.rotateIfDue(ctx); err != nil {
+			r.logger.Error("bind_password.rotation.failed", zap.Error(err), zap.Duration("retry_in", retryDelay))
+			delay = retryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (r *Rotator) rotateIfDue(ctx context.Context) error {
+	if err := r.recoverStaged(ctx); err != nil {
+		return err
+	}
+	lastSet, err := r.account.BindPasswordLastSet(ctx)
+	if err != nil {
+		return fmt.Errorf("check password age: %w", err)
+	}
+	if r.now().Sub(lastSet) < r.interval {
+		return nil
+	}
+	return r.Rotate(ctx)
+}
+
+// Rotate changes the password now.
+func (r *Rotator) Rotate(ctx context.Context) error {
+	if err := r.recoverStaged(ctx); err != nil {
+		return err
+	}
+	current, err := r.store.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("read current secret: %w", err)
+	}
+	next, err := r.generate()
+	if err != nil {
+		return err
+	}
+	if err := r.store.Stage(ctx, next); err != nil {
+		return fmt.Errorf("stage new secret: %w", err)
+	}
+
+	if err := r.account.ChangeBindPassword(ctx, current, next); err != nil {
+		// A rejection by the directory means nothing changed; binding with
+		// the new password would only count towards the lockout threshold.
+		if rejected(err) {
+			if aerr := r.store.Abort(ctx); aerr != nil {
+				return fmt.Errorf("change password in directory: %w (discard staged secret: %v)", err, aerr)
+			}
+			return fmt.Errorf("change password in directory: %w", err)
+		}
+		// Otherwise the change may have been applied even though the
+		// response was lost, or not yet replicated to the DC we verify
+		// against. Deleting the staged secret then would lose the only copy
+		// of the password, so it is kept and recoverStaged decides on the
+		// next run.
+		if verr := r.verify(ctx, next); verr != nil {
+			return fmt.Errorf("change password in directory (new secret kept staged): %w", err)
+		}
+		return r.finish(ctx, next, true)
+	}
+	return r.finish(ctx, next, false)
+}
+
+// rejected reports whether err is the directory's definitive refusal of a
+// password change, e.g. minimum age, history or complexity, a wrong current
+// password or missing rights, as opposed to a lost or failed request.
+func rejected(err error) bool {
+	var lerr *ldap.Error
+	if !errors.As(err, &lerr) {
+		return false
+	}
+	switch lerr.ResultCode {
+	case ldap.LDAPResultConstraintViolation, ldap.LDAPResultUnwillingToPerform,
+		ldap.LDAPResultInvalidCredentials, ldap.LDAPResultInsufficientAccessRights:
+		return true
+	}
+	return false
+}
+
+// finish commits a password the directory already accepted. Unless a bind
+// with it has already succeeded, it is verified after the commit.
+func (r *Rotator) finish(ctx context.Context, next string, verified bool) error {
+	if err := r.store.Commit(ctx); err != nil {
+		return fmt.Errorf("commit new secret (staged copy kept for retry): %w", err)
+	}
+
+	if !verified {
+		if err := r.verify(ctx, next); err != nil {
+			return fmt.Errorf("verify new secret: %w", err)
+		}
+	}
+
+	if err := r.account.ResetConnections(); err != nil {
+		return fmt.Errorf("reset connections: %w", err)
+	}
+	r.logger.Info("bind_password.rotation.complete")
+	return nil
+}
+
+// verify binds with password, retrying to give other domain controllers
+// time to replicate a change.
+func (r *Rotator) verify(ctx context.Context, password string) error {
+	var err error
+	for attempt := 0; attempt < verifyAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.verifyBackoff):
+			}
+		}
+		if err = r.account.VerifyBind(ctx, password); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// recoverStaged completes or discards a rotation interrupted after staging.
+// It binds once with the staged secret, so a secret the directory never
+// accepted costs a single failed bind.
+func (r *Rotator) recoverStaged(ctx context.Context) error {
+	staged, ok, err := r.store.Staged(ctx)
+	if err != nil {
+		return fmt.Errorf("read staged secret: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	if err := r.account.VerifyBind(ctx, staged); err != nil {
+		r.logger.Warn("bind_password.rotation.discard_staged", zap.Error(err))
+		return r.store.Abort(ctx)
+	}
+	r.logger.Info("bind_password.rotation.resume_staged")
+	return r.finish(ctx, staged, true)
+}
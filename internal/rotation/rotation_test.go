@@ -0,0 +1,216 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+)
+
+type fakeAccount struct {
+	password   string
+	lastSet    time.Time
+	changeErr  error
+	applyOnErr bool // apply the change even when returning changeErr
+	resets     int
+	// staleBinds fails this many binds with the current password, as a DC
+	// that has not replicated the change yet would.
+	staleBinds int
+	binds      int
+}
+
+func (a *fakeAccount) BindPasswordLastSet(context.Context) (time.Time, error) { return a.lastSet, nil }
+
+func (a *fakeAccount) ChangeBindPassword(_ context.Context, oldPassword, newPassword string) error {
+	if oldPassword != a.password {
+		return errors.New("invalid credentials")
+	}
+	if a.changeErr != nil {
+		if a.applyOnErr {
+			a.password = newPassword
+		}
+		return a.changeErr
+	}
+	a.password = newPassword
+	return nil
+}
+
+func (a *fakeAccount) VerifyBind(_ context.Context, password string) error {
+	a.binds++
+	if password != a.password {
+		return errors.New("invalid credentials")
+	}
+	if a.staleBinds > 0 {
+		a.staleBinds--
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
+
+func (a *fakeAccount) ResetConnections() error {
+	a.resets++
+	return nil
+}
+
+type fakeStore struct {
+	current   string
+	staged    string
+	hasStaged bool
+	commitErr error
+}
+
+func (s *fakeStore) Get(context.Context) (string, error) { return s.current, nil }
+func (s *fakeStore) Invalidate()                         {}
+
+func (s *fakeStore) Stage(_ context.Context, v string) error {
+	s.staged, s.hasStaged = v, true
+	return nil
+}
+
+func (s *fakeStore) Commit(context.Context) error {
+	if s.commitErr != nil {
+		return s.commitErr
+	}
+	s.current, s.staged, s.hasStaged = s.staged, "", false
+	return nil
+}
+
+func (s *fakeStore) Abort(context.Context) error {
+	s.staged, s.hasStaged = "", false
+	return nil
+}
+
+func (s *fakeStore) Staged(context.Context) (string, bool, error) {
+	return s.staged, s.hasStaged, nil
+}
+
+func newTestRotator(acct *fakeAccount, store *fakeStore) *Rotator {
+	r := New(acct, store, 24*time.Hour, zap.NewNop())
+	r.generate = func() (string, error) { return "N3w!password", nil }
+	r.verifyBackoff = 0
+	return r
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("changes, commits and resets", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old"}
+		store := &fakeStore{current: "old"}
+
+		is.NoErr(newTestRotator(acct, store).Rotate(ctx))
+		is.Equal(acct.password, "N3w!password")
+		is.Equal(store.current, "N3w!password")
+		is.True(!store.hasStaged)
+		is.Equal(acct.resets, 1)
+	})
+
+	t.Run("rejected change is discarded without binding", func(t *testing.T) {
+		is := is.New(t)
+		rejection := fmt.Errorf("change unicodePwd failed: %w", ldap.NewError(ldap.LDAPResultConstraintViolation, errors.New("0000052D: password history")))
+		acct := &fakeAccount{password: "old", lastSet: time.Now(), changeErr: rejection}
+		store := &fakeStore{current: "old"}
+		r := newTestRotator(acct, store)
+
+		is.True(r.Rotate(ctx) != nil)
+		is.True(!store.hasStaged)
+		is.Equal(acct.binds, 0)
+
+		is.NoErr(r.rotateIfDue(ctx)) // nothing staged, not yet due
+		is.Equal(acct.binds, 0)
+		is.Equal(store.current, "old")
+	})
+
+	t.Run("ambiguous failure is discarded on the next run", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", lastSet: time.Now(), changeErr: errors.New("connection reset")}
+		store := &fakeStore{current: "old"}
+		r := newTestRotator(acct, store)
+
+		is.True(r.Rotate(ctx) != nil)
+		is.Equal(acct.password, "old")
+		is.Equal(store.current, "old")
+		is.True(store.hasStaged) // inconclusive: kept for recoverStaged
+		is.Equal(acct.resets, 0)
+
+		binds := acct.binds
+		is.NoErr(r.rotateIfDue(ctx))
+		is.True(!store.hasStaged)
+		is.Equal(store.current, "old")
+		is.Equal(acct.binds, binds+1) // a single bind with the staged secret
+	})
+
+	t.Run("lost response verified after replication", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", changeErr: errors.New("connection reset"), applyOnErr: true, staleBinds: 1}
+		store := &fakeStore{current: "old"}
+
+		is.NoErr(newTestRotator(acct, store).Rotate(ctx))
+		is.Equal(store.current, "N3w!password")
+		is.True(!store.hasStaged)
+		is.Equal(acct.resets, 1)
+	})
+
+	t.Run("lost response still commits an applied change", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", changeErr: errors.New("connection reset"), applyOnErr: true}
+		store := &fakeStore{current: "old"}
+
+		is.NoErr(newTestRotator(acct, store).Rotate(ctx))
+		is.Equal(store.current, "N3w!password")
+		is.Equal(acct.resets, 1)
+	})
+
+	t.Run("failed commit keeps the staged secret for retry", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", lastSet: time.Now()}
+		store := &fakeStore{current: "old", commitErr: errors.New("disk full")}
+		r := newTestRotator(acct, store)
+
+		is.True(r.Rotate(ctx) != nil)
+		is.True(store.hasStaged)
+
+		store.commitErr = nil
+		is.NoErr(r.rotateIfDue(ctx)) // recovers the staged secret; not yet due again
+		is.Equal(store.current, "N3w!password")
+		is.Equal(acct.resets, 1)
+	})
+
+	t.Run("stale staged secret is discarded", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", lastSet: time.Now()}
+		store := &fakeStore{current: "old", staged: "never-applied", hasStaged: true}
+
+		is.NoErr(newTestRotator(acct, store).rotateIfDue(ctx))
+		is.True(!store.hasStaged)
+		is.Equal(store.current, "old")
+	})
+}
+
+func TestRotateIfDue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("skips a recent password", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", lastSet: time.Now().Add(-time.Hour)}
+		store := &fakeStore{current: "old"}
+
+		is.NoErr(newTestRotator(acct, store).rotateIfDue(ctx))
+		is.Equal(acct.password, "old")
+	})
+
+	t.Run("rotates an old password", func(t *testing.T) {
+		is := is.New(t)
+		acct := &fakeAccount{password: "old", lastSet: time.Now().Add(-48 * time.Hour)}
+		store := &fakeStore{current: "old"}
+
+		is.NoErr(newTestRotator(acct, store).rotateIfDue(ctx))
+		is.Equal(acct.password, "N3w!password")
+	})
+}
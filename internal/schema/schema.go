@@ -0,0 +1,250 @@
+// Package schema converts the "custom" fields of member requests and
+// responses to and from directory attributes, as described by the
+// configured attribute mappings, so new fields need no code changes.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// FieldError reports an invalid custom field. It describes a client
+// mistake, so callers should answer 400 rather than 500.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return "custom." + e.Field + ": " + e.Reason
+}
+
+// Schema is an immutable set of attribute mappings.
+type Schema struct {
+	fields []field
+	byName map[string]*field
+}
+
+type field struct {
+	config.AttributeMapping
+	pattern *regexp.Regexp
+}
+
+// New compiles mappings. They are expected to have passed config validation.
+func New(mappings []config.AttributeMapping) (*Schema, error) {
+	s := &Schema{fields: make([]field, len(mappings)), byName: make(map[string]*field, len(mappings))}
+	for i, m := range mappings {
+		f := field{AttributeMapping: m}
+		if m.Pattern != "" {
+			re, err := regexp.Compile("^(?:" + m.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: invalid pattern: %w", m.Field, err)
+			}
+			f.pattern = re
+		}
+		s.fields[i] = f
+		s.byName[m.Field] = &s.fields[i]
+	}
+	return s, nil
+}
+
+// Encode validates custom values and converts them to LDAP attributes, in
+// mapping order. Unknown and read-only fields are rejected; null and empty
+// values are skipped. Every invalid field is reported, joined with
+// errors.Join; see FieldErrors.
+func (s *Schema) Encode(custom map[string]any) ([]ldap.Attribute, error) {
+	return s.encode(custom, false)
+}
+
+// EncodeChanges is Encode for modifying an existing entry: every field
+// present in custom yields an attribute, and null or empty values yield one
+// without values, which clears the attribute when used as a replace.
+func (s *Schema) EncodeChanges(custom map[string]any) ([]ldap.Attribute, error) {
+	return s.encode(custom, true)
+}
+
+func (s *Schema) encode(custom map[string]any, clear bool) ([]ldap.Attribute, error) {
+	var errs []error
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, ok := s.byName[name]
+		if !ok {
+			errs = append(errs, &FieldError{Field: name, Reason: "unknown field"})
+		} else if !f.Writable() {
+			errs = append(errs, &FieldError{Field: name, Reason: "field is read-only"})
+		}
+	}
+
+	var attrs []ldap.Attribute
+	for i := range s.fields {
+		f := &s.fields[i]
+		v, ok := custom[f.Field]
+		if !ok || !f.Writable() {
+			continue
+		}
+		var vals []string
+		if v != nil {
+			var err error
+			if vals, err = f.encode(v); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if len(vals) > 0 || clear {
+			attrs = append(attrs, ldap.Attribute{Type: f.Attribute, Vals: vals})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return attrs, nil
+}
+
+// FieldErrors returns the FieldErrors in err, which may be joined.
+func FieldErrors(err error) []*FieldError {
+	switch e := err.(type) {
+	case *FieldError:
+		return []*FieldError{e}
+	case interface{ Unwrap() []error }:
+		var out []*FieldError
+		for _, inner := range e.Unwrap() {
+			out = append(out, FieldErrors(inner)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// Field returns the API field mapped to attr, compared case-insensitively.
+func (s *Schema) Field(attr string) (string, bool) {
+	for _, f := range s.fields {
+		if strings.EqualFold(f.Attribute, attr) {
+			return f.Field, true
+		}
+	}
+	return "", false
+}
+
+// Attributes returns the LDAP attributes of readable fields, to request in
+// searches.
+func (s *Schema) Attributes() []string {
+	var attrs []string
+	for _, f := range s.fields {
+		if f.Readable() {
+			attrs = append(attrs, f.Attribute)
+		}
+	}
+	return attrs
+}
+
+// Decode returns the readable custom fields present on entry, or nil.
+func (s *Schema) Decode(entry *ldap.Entry) map[string]any {
+	var custom map[string]any
+	for _, f := range s.fields {
+		if !f.Readable() {
+			continue
+		}
+		raw := entry.GetAttributeValues(f.Attribute)
+		if len(raw) == 0 {
+			continue
+		}
+		if custom == nil {
+			custom = make(map[string]any)
+		}
+		if f.MultiValued {
+			vals := make([]any, 0, len(raw))
+			for _, r := range raw {
+				vals = append(vals, f.decode(r))
+			}
+			custom[f.Field] = vals
+		} else {
+			custom[f.Field] = f.decode(raw[0])
+		}
+	}
+	return custom
+}
+
+func (f *field) encode(v any) ([]string, error) {
+	if !f.MultiValued {
+		if _, isList := v.([]any); isList {
+			return nil, &FieldError{Field: f.Field, Reason: "expected a single value, not a list"}
+		}
+		s, err := f.encodeOne(v)
+		if err != nil || s == "" {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+	vals := make([]string, 0, len(list))
+	for _, item := range list {
+		s, err := f.encodeOne(item)
+		if err != nil {
+			return nil, err
+		}
+		if s != "" {
+			vals = append(vals, s)
+		}
+	}
+	return vals, nil
+}
+
+func (f *field) encodeOne(v any) (string, error) {
+	var s string
+	switch f.Type {
+	case config.AttrInt:
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+			return "", &FieldError{Field: f.Field, Reason: "expected an integer"}
+		}
+		s = strconv.FormatInt(int64(n), 10)
+	case config.AttrBool:
+		b, ok := v.(bool)
+		if !ok {
+			return "", &FieldError{Field: f.Field, Reason: "expected true or false"}
+		}
+		s = strings.ToUpper(strconv.FormatBool(b)) // LDAP booleans are TRUE/FALSE
+	default:
+		str, ok := v.(string)
+		if !ok {
+			return "", &FieldError{Field: f.Field, Reason: "expected a string"}
+		}
+		if str == "" {
+			return "", nil
+		}
+		s = str
+	}
+	if f.pattern != nil && !f.pattern.MatchString(s) {
+		return "", &FieldError{Field: f.Field, Reason: "does not match the required pattern"}
+	}
+	return s, nil
+}
+
+func (f *field) decode(raw string) any {
+	switch f.Type {
+	case config.AttrInt:
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return n
+		}
+	case config.AttrBool:
+		return strings.EqualFold(raw, "TRUE")
+	}
+	return raw
+}
@@ -0,0 +1,134 @@
+package schema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/internal/schema"
+)
+
+func newSchema(t *testing.T) *schema.Schema {
+	t.Helper()
+	s, err := schema.New([]config.AttributeMapping{
+		{Field: "major", Attribute: "extensionAttribute1"},
+		{Field: "employeeNumber", Attribute: "employeeNumber", Pattern: "[0-9]{6}"},
+		{Field: "year", Attribute: "extensionAttribute3", Type: config.AttrInt},
+		{Field: "alumni", Attribute: "extensionAttribute4", Type: config.AttrBool},
+		{Field: "clubs", Attribute: "extensionAttribute5", MultiValued: true},
+		{Field: "lastLogon", Attribute: "lastLogonTimestamp", Type: config.AttrInt, Access: config.AccessRead},
+	})
+	if err != nil {
+		t.Fatalf("schema.New: %v", err)
+	}
+	return s
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("converts each type in mapping order", func(t *testing.T) {
+		is := is.New(t)
+		attrs, err := newSchema(t).Encode(map[string]any{
+			"clubs":          []any{"acm", "", "lug"},
+			"alumni":         false,
+			"year":           float64(2027),
+			"employeeNumber": "012345",
+			"major":          "",
+		})
+		is.NoErr(err)
+		is.Equal(attrs, []ldap.Attribute{
+			{Type: "employeeNumber", Vals: []string{"012345"}},
+			{Type: "extensionAttribute3", Vals: []string{"2027"}},
+			{Type: "extensionAttribute4", Vals: []string{"FALSE"}},
+			{Type: "extensionAttribute5", Vals: []string{"acm", "lug"}},
+		})
+	})
+
+	tests := []struct {
+		name   string
+		custom map[string]any
+		field  string
+	}{
+		{"unknown field", map[string]any{"shoeSize": "42"}, "shoeSize"},
+		{"read-only field", map[string]any{"lastLogon": float64(1)}, "lastLogon"},
+		{"pattern must match in full", map[string]any{"employeeNumber": "1234567"}, "employeeNumber"},
+		{"wrong type", map[string]any{"year": "2027"}, "year"},
+		{"fractional int", map[string]any{"year": 20.5}, "year"},
+		{"list for single value", map[string]any{"major": []any{"cs"}}, "major"},
+		{"wrong element type", map[string]any{"clubs": []any{"acm", true}}, "clubs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := newSchema(t).Encode(tt.custom)
+			var fe *schema.FieldError
+			is.True(errors.As(err, &fe))
+			is.Equal(fe.Field, tt.field)
+		})
+	}
+}
+
+func TestEncodeChanges(t *testing.T) {
+	t.Run("null and empty values clear the attribute", func(t *testing.T) {
+		is := is.New(t)
+		attrs, err := newSchema(t).EncodeChanges(map[string]any{
+			"major":  "",
+			"year":   nil,
+			"clubs":  []any{},
+			"alumni": true,
+		})
+		is.NoErr(err)
+		is.Equal(attrs, []ldap.Attribute{
+			{Type: "extensionAttribute1"},
+			{Type: "extensionAttribute3"},
+			{Type: "extensionAttribute4", Vals: []string{"TRUE"}},
+			{Type: "extensionAttribute5", Vals: []string{}},
+		})
+	})
+
+	t.Run("read-only fields are rejected", func(t *testing.T) {
+		is := is.New(t)
+		_, err := newSchema(t).EncodeChanges(map[string]any{"lastLogon": nil})
+		var fe *schema.FieldError
+		is.True(errors.As(err, &fe))
+		is.Equal(fe.Field, "lastLogon")
+	})
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("returns readable fields present on the entry", func(t *testing.T) {
+		is := is.New(t)
+		entry := ldap.NewEntry("cn=jdoe,dc=example,dc=local", map[string][]string{
+			"extensionAttribute1": {"Computer Science"},
+			"extensionAttribute3": {"2027"},
+			"extensionAttribute4": {"TRUE"},
+			"extensionAttribute5": {"acm", "lug"},
+			"lastLogonTimestamp":  {"133000000000000000"},
+		})
+		is.Equal(newSchema(t).Decode(entry), map[string]any{
+			"major":     "Computer Science",
+			"year":      int64(2027),
+			"alumni":    true,
+			"clubs":     []any{"acm", "lug"},
+			"lastLogon": int64(133000000000000000),
+		})
+	})
+
+	t.Run("nil without custom attributes", func(t *testing.T) {
+		is := is.New(t)
+		entry := ldap.NewEntry("cn=jdoe,dc=example,dc=local", map[string][]string{"cn": {"jdoe"}})
+		is.Equal(newSchema(t).Decode(entry), nil)
+	})
+}
+
+func TestAttributesSkipsWriteOnly(t *testing.T) {
+	is := is.New(t)
+	s, err := schema.New([]config.AttributeMapping{
+		{Field: "major", Attribute: "extensionAttribute1"},
+		{Field: "pin", Attribute: "extensionAttribute9", Access: config.AccessWrite},
+	})
+	is.NoErr(err)
+	is.Equal(s.Attributes(), []string{"extensionAttribute1"})
+}
@@ -0,0 +1,120 @@
+// Package secrets resolves credentials from a static value, a file (such as a
+// Docker or Kubernetes secret mount) or an external credential-helper command.
+// Sources are read lazily so a rotated secret is picked up by the next bind.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandTimeout bounds how long a credential helper may run.
+const commandTimeout = 10 * time.Second
+
+// Source supplies a secret on demand.
+type Source interface {
+	// Get returns the current secret.
+	Get(ctx context.Context) (string, error)
+	// Invalidate drops any cached value so the next Get re-reads it, e.g.
+	// after the directory rejected the credential.
+	Invalidate()
+}
+
+// New returns the Source configured by exactly one of value, file or
+// command. With none set it returns a Source that yields "".
+func New(value, file, command string) (Source, error) {
+	set := 0
+	for _, s := range []string{value, file, command} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, errors.New("only one of a value, a file or a command may be configured")
+	}
+	switch {
+	case file != "":
+		return fileSource(file), nil
+	case command != "":
+		args, err := splitCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		return &commandSource{name: args[0], args: args[1:]}, nil
+	default:
+		return staticSource(value), nil
+	}
+}
+
+// splitCommand splits command into a program and its arguments.
+func splitCommand(command string) ([]string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("secret command is blank")
+	}
+	return args, nil
+}
+
+type staticSource string
+
+func (s staticSource) Get(context.Context) (string, error) { return string(s), nil }
+func (staticSource) Invalidate()                           {}
+
+// fileSource re-reads the file on every Get; secret mounts are small and
+// rotation tooling replaces them in place.
+type fileSource string
+
+func (f fileSource) Get(context.Context) (string, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (fileSource) Invalidate() {}
+
+// commandSource runs a credential helper and caches its stdout until
+// invalidated.
+type commandSource struct {
+	name string
+	args []string
+
+	mu     sync.Mutex
+	cached string
+	ok     bool
+}
+
+func (c *commandSource) Get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ok {
+		return c.cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %s failed: %w: %s", c.name, err, strings.TrimSpace(stderr.String()))
+	}
+	c.cached = strings.TrimRight(stdout.String(), "\r\n")
+	c.ok = true
+	return c.cached, nil
+}
+
+func (c *commandSource) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cached, c.ok = "", false
+}
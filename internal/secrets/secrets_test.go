@@ -0,0 +1,83 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/internal/secrets"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("rejects more than one source", func(t *testing.T) {
+		is := is.New(t)
+		_, err := secrets.New("pw", "/run/secrets/pw", "")
+		is.True(err != nil)
+	})
+
+	t.Run("rejects a blank command", func(t *testing.T) {
+		is := is.New(t)
+		_, err := secrets.New("", "", " \t")
+		is.True(err != nil)
+	})
+
+	t.Run("static value", func(t *testing.T) {
+		is := is.New(t)
+		src, err := secrets.New("pw", "", "")
+		is.NoErr(err)
+		got, err := src.Get(context.Background())
+		is.NoErr(err)
+		is.Equal(got, "pw")
+	})
+}
+
+func TestFileSourceRereads(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(t.TempDir(), "bind_password")
+	is.NoErr(os.WriteFile(path, []byte("first\n"), 0o600))
+
+	src, err := secrets.New("", path, "")
+	is.NoErr(err)
+	got, err := src.Get(context.Background())
+	is.NoErr(err)
+	is.Equal(got, "first")
+
+	is.NoErr(os.WriteFile(path, []byte("rotated\n"), 0o600))
+	got, err = src.Get(context.Background())
+	is.NoErr(err)
+	is.Equal(got, "rotated")
+}
+
+func TestCommandSourceCachesUntilInvalidated(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pw")
+	is.NoErr(os.WriteFile(path, []byte("first"), 0o600))
+
+	src, err := secrets.New("", "", "cat "+path)
+	is.NoErr(err)
+	got, err := src.Get(context.Background())
+	is.NoErr(err)
+	is.Equal(got, "first")
+
+	is.NoErr(os.WriteFile(path, []byte("rotated"), 0o600))
+	got, err = src.Get(context.Background())
+	is.NoErr(err)
+	is.Equal(got, "first") // cached
+
+	src.Invalidate()
+	got, err = src.Get(context.Background())
+	is.NoErr(err)
+	is.Equal(got, "rotated")
+}
+
+func TestCommandSourceFailure(t *testing.T) {
+	is := is.New(t)
+	src, err := secrets.New("", "", "false")
+	is.NoErr(err)
+	_, err = src.Get(context.Background())
+	is.True(err != nil)
+}
@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store is a Source that password rotation can update. Stage durably
+// records a candidate without making it current, Commit makes the staged
+// value current and Abort discards it. Staged reports a candidate left
+// behind by an interrupted rotation.
+type Store interface {
+	Source
+	Stage(ctx context.Context, value string) error
+	Commit(ctx context.Context) error
+	Abort(ctx context.Context) error
+	Staged(ctx context.Context) (string, bool, error)
+}
+
+// NewStore returns a Store backed by file, or by command for reads paired
+// with storeCommand, which receives the new secret on stdin, for writes.
+func NewStore(file, command, storeCommand string) (Store, error) {
+	switch {
+	case file != "":
+		return &fileStore{path: file}, nil
+	case command != "" && storeCommand != "":
+		args, err := splitCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		storeArgs, err := splitCommand(storeCommand)
+		if err != nil {
+			return nil, fmt.Errorf("store command: %w", err)
+		}
+		return &commandStore{
+			commandSource: &commandSource{name: args[0], args: args[1:]},
+			storeName:     storeArgs[0],
+			storeArgs:     storeArgs[1:],
+		}, nil
+	default:
+		return nil, errors.New("a writable secret needs a file, or a command together with a store command")
+	}
+}
+
+// fileStore stages the candidate next to the secret file as "<path>.next"
+// so a crash between changing the directory and committing loses nothing.
+type fileStore struct {
+	path string
+}
+
+func (f *fileStore) next() string { return f.path + ".next" }
+
+func (f *fileStore) Get(ctx context.Context) (string, error) {
+	return fileSource(f.path).Get(ctx)
+}
+
+func (f *fileStore) Invalidate() {}
+
+func (f *fileStore) Stage(_ context.Context, value string) error {
+	return writeFileAtomic(f.next(), []byte(value+"\n"))
+}
+
+func (f *fileStore) Commit(context.Context) error {
+	if err := os.Rename(f.next(), f.path); err != nil {
+		return fmt.Errorf("commit secret file: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) Abort(context.Context) error {
+	if err := os.Remove(f.next()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("discard staged secret: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) Staged(ctx context.Context) (string, bool, error) {
+	v, err := fileSource(f.next()).Get(ctx)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return v, true, nil
+}
+
+// commandStore stages in memory only; the credential helper is expected to
+// persist atomically when the store command runs.
+type commandStore struct {
+	*commandSource
+	storeName string
+	storeArgs []string
+
+	mu      sync.Mutex
+	pending string
+}
+
+func (c *commandStore) Stage(_ context.Context, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = value
+	return nil
+}
+
+func (c *commandStore) Commit(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == "" {
+		return errors.New("no staged secret to commit")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.storeName, c.storeArgs...)
+	cmd.Stdin = strings.NewReader(c.pending + "\n")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("credential store helper %s failed: %w: %s", c.storeName, err, strings.TrimSpace(stderr.String()))
+	}
+	c.pending = ""
+	c.Invalidate()
+	return nil
+}
+
+func (c *commandStore) Abort(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = ""
+	return nil
+}
+
+func (c *commandStore) Staged(context.Context) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending, c.pending != "", nil
+}
+
+// writeFileAtomic replaces path with data, readable only by the owner.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("stage secret: %w", err)
+	}
+	return nil
+}
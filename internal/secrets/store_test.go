@@ -0,0 +1,97 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/internal/secrets"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commit replaces the secret", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "bind_password")
+		is.NoErr(os.WriteFile(path, []byte("old\n"), 0o600))
+
+		st, err := secrets.NewStore(path, "", "")
+		is.NoErr(err)
+		is.NoErr(st.Stage(ctx, "new"))
+
+		got, err := st.Get(ctx)
+		is.NoErr(err)
+		is.Equal(got, "old") // staging does not change the current secret
+
+		staged, ok, err := st.Staged(ctx)
+		is.NoErr(err)
+		is.True(ok)
+		is.Equal(staged, "new")
+
+		is.NoErr(st.Commit(ctx))
+		got, err = st.Get(ctx)
+		is.NoErr(err)
+		is.Equal(got, "new")
+		_, ok, err = st.Staged(ctx)
+		is.NoErr(err)
+		is.True(!ok)
+
+		info, err := os.Stat(path)
+		is.NoErr(err)
+		is.Equal(info.Mode().Perm(), os.FileMode(0o600))
+	})
+
+	t.Run("abort keeps the secret", func(t *testing.T) {
+		is := is.New(t)
+		path := filepath.Join(t.TempDir(), "bind_password")
+		is.NoErr(os.WriteFile(path, []byte("old\n"), 0o600))
+
+		st, err := secrets.NewStore(path, "", "")
+		is.NoErr(err)
+		is.NoErr(st.Stage(ctx, "new"))
+		is.NoErr(st.Abort(ctx))
+
+		got, err := st.Get(ctx)
+		is.NoErr(err)
+		is.Equal(got, "old")
+		_, ok, err := st.Staged(ctx)
+		is.NoErr(err)
+		is.True(!ok)
+	})
+}
+
+func TestCommandStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	is.NoErr(os.WriteFile(path, []byte("old\n"), 0o600))
+	script := filepath.Join(dir, "store.sh")
+	is.NoErr(os.WriteFile(script, []byte("#!/bin/sh\ncat > "+path+"\n"), 0o700))
+
+	st, err := secrets.NewStore("", "cat "+path, script)
+	is.NoErr(err)
+	got, err := st.Get(ctx)
+	is.NoErr(err)
+	is.Equal(got, "old")
+
+	is.NoErr(st.Stage(ctx, "new"))
+	is.NoErr(st.Commit(ctx))
+	got, err = st.Get(ctx)
+	is.NoErr(err)
+	is.Equal(got, "new") // commit drops the cached value
+}
+
+func TestNewStoreRequiresWritableBackend(t *testing.T) {
+	is := is.New(t)
+	_, err := secrets.NewStore("", "cat /dev/null", "")
+	is.True(err != nil)
+	_, err = secrets.NewStore("", "cat /dev/null", " ")
+	is.True(err != nil)
+	_, err = secrets.NewStore("", " ", "tee /dev/null")
+	is.True(err != nil)
+}
@@ -0,0 +1,132 @@
+// Package servertls builds the HTTPS listener's TLS configuration. The
+// certificate, key and client CA bundle are re-read when their files change,
+// so renewed certificates are served without a restart.
+package servertls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Client certificate policies for ClientAuth.
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// checkInterval bounds how often the files are stat'ed during handshakes.
+const checkInterval = time.Second
+
+// Reloader serves the current certificate and client CA pool.
+type Reloader struct {
+	certFile, keyFile, clientCAFile string
+	clientAuth                      tls.ClientAuthType
+	logger                          *zap.Logger
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  string
+	lastCheck time.Time
+}
+
+// New loads the files once, failing if they are unusable. clientCAFile may be
+// empty to disable client certificates; clientAuth is ClientAuthRequire or
+// ClientAuthOptional.
+func New(certFile, keyFile, clientCAFile, clientAuth string, logger *zap.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile, logger: logger}
+	if clientCAFile != "" {
+		switch clientAuth {
+		case ClientAuthOptional:
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		default:
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	cfg, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.config = cfg
+	r.modTimes = r.stat()
+	r.lastCheck = time.Now()
+	return r, nil
+}
+
+// TLSConfig returns a tls.Config for http.Server that resolves the current
+// certificate and client CAs on every handshake.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// current returns the active configuration, reloading it first if the files
+// changed. A failed reload is logged and the previous configuration kept.
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.lastCheck) < checkInterval {
+		return r.config
+	}
+	r.lastCheck = time.Now()
+	if mt := r.stat(); mt != r.modTimes {
+		cfg, err := r.load()
+		if err != nil {
+			r.logger.Error("http.tls.reload_failed", zap.Error(err))
+			return r.config
+		}
+		r.config, r.modTimes = cfg, mt
+		r.logger.Info("http.tls.reloaded", zap.String("cert", r.certFile))
+	}
+	return r.config
+}
+
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	// The config returned by GetConfigForClient replaces the server's for
+	// the handshake, so it must offer HTTP/2 itself.
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.clientCAFile != "" {
+		pemData, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("failed to parse client CA certificate(s) from %s", r.clientCAFile)
+		}
+		cfg.ClientCAs = pool
+	}
+	return cfg, nil
+}
+
+// stat summarizes the modification state of the watched files.
+func (r *Reloader) stat() string {
+	var fp string
+	for _, p := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if p == "" {
+			continue
+		}
+		if fi, err := os.Stat(p); err == nil {
+			fp += p + fi.ModTime().String() + fmt.Sprint(fi.Size()) + "|"
+		}
+	}
+	return fp
+}
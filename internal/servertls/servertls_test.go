@@ -0,0 +1,131 @@
+package servertls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/internal/servertls"
+)
+
+// writeCert writes a self-signed certificate for cn to dir and returns the
+// certificate and key paths.
+func writeCert(t *testing.T, dir, cn string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func servedCN(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	current, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(current.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloader(t *testing.T) {
+	t.Run("reloads a changed certificate", func(t *testing.T) {
+		is := is.New(t)
+		dir := t.TempDir()
+		certPath, keyPath := writeCert(t, dir, "first")
+
+		r, err := servertls.New(certPath, keyPath, "", "", zap.NewNop())
+		is.NoErr(err)
+		cfg := r.TLSConfig()
+		is.Equal(servedCN(t, cfg), "first")
+
+		writeCert(t, dir, "second")
+		later := time.Now().Add(time.Minute)
+		is.NoErr(os.Chtimes(certPath, later, later))
+		time.Sleep(1100 * time.Millisecond) // files are checked at most once a second
+		is.Equal(servedCN(t, cfg), "second")
+	})
+
+	t.Run("keeps serving when the new files are broken", func(t *testing.T) {
+		is := is.New(t)
+		dir := t.TempDir()
+		certPath, keyPath := writeCert(t, dir, "good")
+
+		r, err := servertls.New(certPath, keyPath, "", "", zap.NewNop())
+		is.NoErr(err)
+		cfg := r.TLSConfig()
+
+		is.NoErr(os.WriteFile(certPath, []byte("garbage"), 0o600))
+		time.Sleep(1100 * time.Millisecond)
+		is.Equal(servedCN(t, cfg), "good")
+	})
+
+	t.Run("client CA enables verification", func(t *testing.T) {
+		is := is.New(t)
+		dir := t.TempDir()
+		certPath, keyPath := writeCert(t, dir, "server")
+
+		r, err := servertls.New(certPath, keyPath, certPath, servertls.ClientAuthRequire, zap.NewNop())
+		is.NoErr(err)
+		current, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		is.NoErr(err)
+		is.Equal(current.ClientAuth, tls.RequireAndVerifyClientCert)
+		is.True(current.ClientCAs != nil)
+	})
+
+	t.Run("offers HTTP/2", func(t *testing.T) {
+		is := is.New(t)
+		dir := t.TempDir()
+		certPath, keyPath := writeCert(t, dir, "server")
+
+		r, err := servertls.New(certPath, keyPath, "", "", zap.NewNop())
+		is.NoErr(err)
+		current, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		is.NoErr(err)
+		is.Equal(current.NextProtos, []string{"h2", "http/1.1"})
+	})
+
+	t.Run("missing files fail at startup", func(t *testing.T) {
+		is := is.New(t)
+		_, err := servertls.New("/nonexistent/tls.crt", "/nonexistent/tls.key", "", "", zap.NewNop())
+		is.True(err != nil)
+	})
+}
@@ -0,0 +1,172 @@
+// Package usernames derives candidate account names for registrants and
+// applies the reserved-name blocklist.
+package usernames
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxLength is AD's sAMAccountName limit.
+const maxLength = 20
+
+// maxCandidates bounds the OR-filter sent to the directory.
+const maxCandidates = 30
+
+// Placeholders accepted in patterns.
+var placeholders = map[string]bool{"first": true, "last": true, "f": true, "l": true, "netid": true}
+
+var placeholder = regexp.MustCompile(`\{([a-z]*)\}`)
+
+// Input holds what is known about the registrant.
+type Input struct {
+	Username  string
+	GivenName string
+	Surname   string
+	NetID     string
+}
+
+// Suggester turns Input into candidate usernames.
+type Suggester struct {
+	patterns []string
+	reserved map[string]bool
+}
+
+// ParsePatterns splits a comma-separated pattern list such as
+// "{netid},{first}.{last},{f}{last}" and checks its placeholders.
+func ParsePatterns(list string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(p, -1) {
+			if !placeholders[m[1]] {
+				return nil, fmt.Errorf("pattern %q: unknown placeholder {%s} (want {first}, {last}, {f}, {l} or {netid})", p, m[1])
+			}
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// ParseReserved splits a comma-separated blocklist into a lower-cased set.
+func ParseReserved(list string) map[string]bool {
+	reserved := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			reserved[name] = true
+		}
+	}
+	return reserved
+}
+
+// New returns a Suggester for the given pattern list and blocklist.
+func New(patterns, reserved string) (*Suggester, error) {
+	p, err := ParsePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Suggester{patterns: p, reserved: ParseReserved(reserved)}, nil
+}
+
+// Reserved reports whether name is on the blocklist.
+func (s *Suggester) Reserved(name string) bool {
+	return s.reserved[strings.ToLower(name)]
+}
+
+// Candidates returns usernames derived from in, most preferred first: each
+// pattern in order, then numbered variants. Reserved names and in.Username
+// itself are left out.
+func (s *Suggester) Candidates(in Input) []string {
+	first, last := normalize(in.GivenName), normalize(in.Surname)
+	values := map[string]string{
+		"first": first,
+		"last":  last,
+		"f":     initial(first),
+		"l":     initial(last),
+		"netid": normalize(in.NetID),
+	}
+
+	seen := map[string]bool{strings.ToLower(in.Username): true}
+	var out []string
+	add := func(name string) {
+		name = fit(name, "")
+		if len(name) < 2 || seen[name] || s.reserved[name] || len(out) >= maxCandidates {
+			return
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+
+	// Numbered variants come from the requested name and the first pattern
+	// that does not already end in a digit, as NetIDs usually do.
+	var bases []string
+	addBase := func(name string) {
+		name = fit(name, "")
+		if len(bases) < 2 && len(name) >= 2 && !s.reserved[name] && !unicode.IsDigit(rune(name[len(name)-1])) {
+			bases = append(bases, name)
+		}
+	}
+	addBase(in.Username)
+	for _, p := range s.patterns {
+		name, ok := expand(p, values)
+		if !ok {
+			continue
+		}
+		add(name)
+		addBase(name)
+	}
+	for n := 2; n <= 9; n++ {
+		for _, base := range bases {
+			add(fit(base, strconv.Itoa(n)))
+		}
+	}
+	return out
+}
+
+// expand substitutes values into pattern; it fails when a placeholder has
+// no value, so "{first}.{last}" is skipped without a surname.
+func expand(pattern string, values map[string]string) (string, bool) {
+	ok := true
+	name := placeholder.ReplaceAllStringFunc(pattern, func(m string) string {
+		v := values[m[1:len(m)-1]]
+		if v == "" {
+			ok = false
+		}
+		return v
+	})
+	return name, ok
+}
+
+// normalize lower-cases s and keeps the characters usernames may contain.
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func initial(s string) string {
+	if s == "" {
+		return ""
+	}
+	return s[:1]
+}
+
+// fit truncates base so base+suffix is a valid sAMAccountName length and
+// does not end with a period.
+func fit(base, suffix string) string {
+	base = normalize(base)
+	if len(base)+len(suffix) > maxLength {
+		base = base[:maxLength-len(suffix)]
+	}
+	return strings.TrimRight(base, ".") + suffix
+}
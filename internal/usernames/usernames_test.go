@@ -0,0 +1,50 @@
+package usernames_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/internal/usernames"
+)
+
+func TestParsePatterns(t *testing.T) {
+	is := is.New(t)
+	p, err := usernames.ParsePatterns(" {netid}, {first}.{last} ,,")
+	is.NoErr(err)
+	is.Equal(p, []string{"{netid}", "{first}.{last}"})
+
+	_, err = usernames.ParsePatterns("{first}{middle}")
+	is.True(err != nil)
+}
+
+func TestCandidates(t *testing.T) {
+	t.Run("patterns then numbered variants", func(t *testing.T) {
+		is := is.New(t)
+		s, err := usernames.New("{netid},{f}{last},{first}.{last}", "admin")
+		is.NoErr(err)
+		got := s.Candidates(usernames.Input{Username: "jdoe", GivenName: "Jane", Surname: "O'Doe", NetID: "JODOE2"})
+		is.Equal(got[:6], []string{"jodoe2", "jodoe", "jane.odoe", "jdoe2", "jdoe3", "jodoe3"})
+	})
+
+	t.Run("skips patterns without values and reserved names", func(t *testing.T) {
+		is := is.New(t)
+		s, err := usernames.New("{netid},{first}", "root")
+		is.NoErr(err)
+		got := s.Candidates(usernames.Input{GivenName: "Root"})
+		is.Equal(len(got), 0)
+		is.True(s.Reserved("ROOT"))
+	})
+
+	t.Run("fits the sAMAccountName limit", func(t *testing.T) {
+		is := is.New(t)
+		s, err := usernames.New("{first}.{last}", "")
+		is.NoErr(err)
+		got := s.Candidates(usernames.Input{GivenName: "Maximiliana", Surname: "Featherstonehaugh"})
+		for _, name := range got {
+			is.True(len(name) <= 20)
+		}
+		is.Equal(got[0], "maximiliana.feathers")
+		is.Equal(got[1], "maximiliana.feather2")
+	})
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-ldap/ldap/v3"
 	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/internal/schema"
 )
 
 // AddUser creates a new LDAP entry for the provided user information.
@@ -15,47 +17,150 @@ func (c *Client) AddUser(ctx context.Context, u *UserInfo) error {
 	ctxTimeout, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	conn, err := c.dialAndBind(ctxTimeout)
+	logger := c.log(ctx)
+	st := c.current()
+	tmpl, err := st.template(u)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
+	if u.OrganizationalUnit == "" && tmpl.OU != "" {
+		withOU := *u
+		withOU.OrganizationalUnit = tmpl.OU
+		u = &withOU
+	}
 
-	dn := c.buildUserDN(u)
-	req := c.buildAddRequest(dn, u)
+	conn, err := c.acquire(ctxTimeout, roleWrite)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
 
-	if err := conn.Add(req); err != nil {
-		if c.logger != nil {
-			c.logger.Error("ldap add failed", zap.Error(err), zap.String("dn", dn), zap.String("username", u.Username))
+	// Refuse before creating the entry rather than leave it without a password.
+	if u.Password != "" {
+		if err := requireEncrypted(conn); err != nil {
+			return err
 		}
-		return fmt.Errorf("ldap add failed: %w", err)
 	}
 
+	verr := &ValidationError{}
+	dn, ou := c.buildUserDN(st, u, verr)
+	if verr.ErrOrNil() == nil { // an invalid OU is already reported
+		if err := c.checkOU(ctx, conn, st, ou, verr); err != nil {
+			return err
+		}
+	}
+	if st.usernames.Reserved(u.Username) {
+		verr.Add("username", "%q is reserved", u.Username)
+	}
+	c.checkUPNSuffix(ctx, conn, st, u, verr)
+	req, err := c.buildAddRequest(st, tmpl, dn, u, verr)
+	if err != nil {
+		return err
+	}
+	c.checkAddRequest(ctx, conn, st, req, verr)
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+	if err := c.checkUnique(ctx, conn, st, req); err != nil {
+		return err
+	}
 	if u.Password != "" {
-		if err := c.setUnicodePwd(conn, dn, u.Password); err != nil {
-			if c.logger != nil {
-				c.logger.Error("set unicodePwd failed", zap.Error(err), zap.String("dn", dn), zap.String("username", u.Username))
-			}
+		if err := c.checkPasswordPolicy(ctx, conn, tmpl, req, u); err != nil {
 			return err
 		}
-		if err := c.enableAccount(conn, dn); err != nil {
-			if c.logger != nil {
-				c.logger.Warn("enable account failed", zap.Error(err), zap.String("dn", dn), zap.String("username", u.Username))
-			}
+		// Screened again by setUnicodePwd; checking first avoids leaving
+		// an account without a password.
+		if err := c.screenPassword(u.Password); err != nil {
 			return err
 		}
 	}
 
-	if c.logger != nil {
-		c.logger.Info("user added", zap.String("dn", dn), zap.String("username", u.Username))
+	if err := conn.Add(req); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			return c.addConflict(ctx, conn, st, dn, u.Username)
+		}
+		logger.Error("ldap add failed", zap.Error(err), zap.String("dn", dn), zap.String("username", u.Username))
+		return fmt.Errorf("ldap add failed: %w", err)
+	}
+
+	if err := c.setUp(ctx, conn, tmpl, dn, u); err != nil {
+		return err
 	}
 
+	logger.Info("user added", zap.String("dn", dn), zap.String("username", u.Username))
+
 	return nil
 }
 
-func (c *Client) buildAddRequest(dn string, u *UserInfo) *ldap.AddRequest {
+// PartialCreateError reports an account that was created but could neither
+// be set up completely nor removed again. Remaining lists the steps still
+// to do by hand, starting with the one that failed with Err.
+type PartialCreateError struct {
+	DN        string
+	Remaining []string
+	Err       error
+}
+
+func (e *PartialCreateError) Error() string {
+	return fmt.Sprintf("%s was created but could not be set up (%v) or removed; still to do: %s", e.DN, e.Err, strings.Join(e.Remaining, "; "))
+}
+
+func (e *PartialCreateError) Unwrap() error { return e.Err }
+
+type provisioner interface {
+	passwordModifier
+	Del(*ldap.DelRequest) error
+}
+
+// setUp runs the steps that follow the add: password, userAccountControl,
+// password expiry and template groups. When one fails the entry is deleted
+// so a retry starts clean; if that fails too, a PartialCreateError names
+// what is left to do.
+func (c *Client) setUp(ctx context.Context, conn provisioner, tmpl *provisioning, dn string, u *UserInfo) error {
+	type step struct {
+		name string
+		run  func() error
+	}
+	var steps []step
+	if u.Password != "" {
+		steps = append(steps, step{"set password", func() error { return c.setUnicodePwd(conn, dn, u.Password) }})
+	}
+	if u.Password != "" || tmpl.UserAccountControl != 0 {
+		uac := tmpl.userAccountControl()
+		steps = append(steps, step{fmt.Sprintf("set userAccountControl to %d", uac), func() error { return c.setUserAccountControl(conn, dn, uac) }})
+	}
+	if u.Password != "" && u.MustChangePassword {
+		steps = append(steps, step{"expire password", func() error { return c.expirePassword(conn, dn) }})
+	}
+	for _, group := range tmpl.Groups {
+		steps = append(steps, step{"add to group " + group, func() error { return c.addToGroup(conn, group, dn) }})
+	}
+
+	logger := c.log(ctx)
+	for i, s := range steps {
+		err := s.run()
+		if err == nil {
+			continue
+		}
+		logger.Error("ldap.add.setup_failed", zap.Error(err), zap.String("dn", dn), zap.String("step", s.name))
+		if derr := conn.Del(ldap.NewDelRequest(dn, nil)); derr != nil {
+			logger.Error("ldap.add.rollback_failed", zap.Error(derr), zap.String("dn", dn))
+			remaining := make([]string, 0, len(steps)-i)
+			for _, r := range steps[i:] {
+				remaining = append(remaining, r.name)
+			}
+			return &PartialCreateError{DN: dn, Remaining: remaining, Err: err}
+		}
+		return fmt.Errorf("%w (account %s removed)", err, dn)
+	}
+	return nil
+}
+
+// buildAddRequest assembles the entry for u. Invalid custom fields are
+// recorded in verr; the returned error is for everything else.
+func (c *Client) buildAddRequest(st *clientState, tmpl *provisioning, dn string, u *UserInfo, verr *ValidationError) (*ldap.AddRequest, error) {
 	req := ldap.NewAddRequest(dn, nil)
-	req.Attribute("objectClass", []string{"top", "person", "organizationalPerson", "user"})
+	req.Attribute("objectClass", tmpl.objectClasses())
 	req.Attribute("cn", []string{u.Username})
 
 	sn := u.Surname
@@ -81,21 +186,49 @@ func (c *Client) buildAddRequest(dn string, u *UserInfo) *ldap.AddRequest {
 	if u.Description != "" {
 		req.Attribute("description", []string{u.Description})
 	}
-	if u.CustomAttrs.Major != "" {
-		req.Attribute("extensionAttribute1", []string{u.CustomAttrs.Major})
+	custom, err := st.schema.Encode(u.CustomAttrs)
+	for _, fe := range schema.FieldErrors(err) {
+		verr.Add("custom."+fe.Field, "%s", fe.Reason)
+	}
+	for _, attr := range custom {
+		req.Attribute(attr.Type, attr.Vals)
+	}
+
+	if suffix := st.upnSuffix(u); suffix != "" {
+		req.Attribute("userPrincipalName", []string{u.Username + "@" + suffix})
+	}
+
+	// Template values fill in whatever the request left unset.
+	defaults, err := tmpl.defaults(u)
+	if err != nil {
+		return nil, err
 	}
-	if u.CustomAttrs.College != "" {
-		req.Attribute("extensionAttribute2", []string{u.CustomAttrs.College})
+	for _, attr := range defaults {
+		if !hasAttribute(req, attr.Type) {
+			req.Attribute(attr.Type, attr.Vals)
+		}
 	}
 
-	dcParts := parseDCParts(c.cfg.BaseDN)
-	if len(dcParts) > 0 {
-		domain := strings.Join(dcParts, ".")
-		upn := fmt.Sprintf("%s@%s", u.Username, domain)
-		req.Attribute("userPrincipalName", []string{upn})
+	return req, nil
+}
+
+func hasAttribute(req *ldap.AddRequest, name string) bool {
+	for _, a := range req.Attributes {
+		if strings.EqualFold(a.Type, name) {
+			return true
+		}
 	}
+	return false
+}
 
-	return req
+// addToGroup adds memberDN to the group's member attribute.
+func (c *Client) addToGroup(conn ldapModifier, groupDN, memberDN string) error {
+	mr := ldap.NewModifyRequest(groupDN, nil)
+	mr.Add("member", []string{memberDN})
+	if err := conn.Modify(mr); err != nil {
+		return fmt.Errorf("add to group %s failed: %w", groupDN, err)
+	}
+	return nil
 }
 
 func parseDCParts(baseDN string) []string {
@@ -0,0 +1,68 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// fakeProvisioner fails modifies of the DN in failOn and records deletes.
+type fakeProvisioner struct {
+	mockModifier
+	failOn  string
+	deleted []string
+	delErr  error
+}
+
+func (f *fakeProvisioner) Modify(req *ldap.ModifyRequest) error {
+	if req.DN == f.failOn {
+		return ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("denied"))
+	}
+	return f.mockModifier.Modify(req)
+}
+
+func (f *fakeProvisioner) Del(req *ldap.DelRequest) error {
+	f.deleted = append(f.deleted, req.DN)
+	return f.delErr
+}
+
+func TestSetUp(t *testing.T) {
+	const dn = "CN=jdoe,dc=example,dc=local"
+	tmpl := &provisioning{Template: config.Template{Groups: []string{"CN=Members,dc=example,dc=local", "CN=Lab,dc=example,dc=local"}}}
+	u := &UserInfo{Username: "jdoe", Password: "S3cret!pw", MustChangePassword: true}
+
+	t.Run("all steps", func(t *testing.T) {
+		is := is.New(t)
+		conn := &fakeProvisioner{}
+		is.NoErr((&Client{}).setUp(context.Background(), conn, tmpl, dn, u))
+		is.Equal(conn.calls, 5) // password, UAC, expiry, two groups
+		is.Equal(len(conn.deleted), 0)
+	})
+
+	t.Run("failed step removes the account", func(t *testing.T) {
+		is := is.New(t)
+		conn := &fakeProvisioner{failOn: "CN=Lab,dc=example,dc=local"}
+		err := (&Client{}).setUp(context.Background(), conn, tmpl, dn, u)
+		var lerr *ldap.Error
+		is.True(errors.As(err, &lerr))
+		is.Equal(lerr.ResultCode, uint16(ldap.LDAPResultInsufficientAccessRights))
+		is.True(strings.HasSuffix(err.Error(), "(account CN=jdoe,dc=example,dc=local removed)"))
+		is.Equal(conn.deleted, []string{dn})
+	})
+
+	t.Run("failed removal reports what is left", func(t *testing.T) {
+		is := is.New(t)
+		conn := &fakeProvisioner{failOn: "CN=Members,dc=example,dc=local", delErr: errors.New("busy")}
+		err := (&Client{}).setUp(context.Background(), conn, tmpl, dn, u)
+		var partial *PartialCreateError
+		is.True(errors.As(err, &partial))
+		is.Equal(partial.DN, dn)
+		is.Equal(partial.Remaining, []string{"add to group CN=Members,dc=example,dc=local", "add to group CN=Lab,dc=example,dc=local"})
+	})
+}
@@ -0,0 +1,128 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/lugatuic/goberus/internal/usernames"
+)
+
+// maxSuggestions bounds the alternatives offered for a taken name.
+const maxSuggestions = 5
+
+// AvailabilityRequest describes the name a registrant wants and what is
+// known about them for deriving alternatives.
+type AvailabilityRequest struct {
+	Username  string
+	GivenName string
+	Surname   string
+	NetID     string
+}
+
+// Availability reports whether a username can be registered. Reason is
+// "taken", "reserved" or "invalid" when it cannot, and Suggestions lists
+// available alternatives.
+type Availability struct {
+	Username    string   `json:"username"`
+	Available   bool     `json:"available"`
+	Reason      string   `json:"reason,omitempty"`
+	Detail      string   `json:"detail,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// CheckAvailability checks req.Username and the candidates derived from
+// req with a single directory search. Without a username it only suggests.
+func (c *Client) CheckAvailability(ctx context.Context, req AvailabilityRequest) (*Availability, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
+
+	st := c.current()
+	name := strings.ToLower(req.Username)
+	res := &Availability{Username: name}
+	if name != "" {
+		res.Reason, res.Detail = st.unusable(name)
+	}
+
+	candidates := st.usernames.Candidates(usernames.Input{
+		Username:  name,
+		GivenName: req.GivenName,
+		Surname:   req.Surname,
+		NetID:     req.NetID,
+	})
+	names := candidates
+	if name != "" && res.Reason == "" {
+		names = append([]string{name}, candidates...)
+	}
+	if len(names) == 0 {
+		return res, nil
+	}
+
+	conn, err := c.acquire(ctxTimeout, roleRead)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	taken, err := c.takenNames(ctx, conn, st, names)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" && res.Reason == "" {
+		if taken[name] {
+			res.Reason = "taken"
+		} else {
+			res.Available = true
+			return res, nil
+		}
+	}
+	for _, cand := range candidates {
+		if !taken[cand] {
+			res.Suggestions = append(res.Suggestions, cand)
+			if len(res.Suggestions) == maxSuggestions {
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// takenNames returns which of names are already used as a sAMAccountName
+// anywhere in the domain.
+func (c *Client) takenNames(ctx context.Context, conn searcher, st *clientState, names []string) (map[string]bool, error) {
+	base := c.domainBase(ctx, conn, st)
+	var filter strings.Builder
+	filter.WriteString("(|")
+	for _, n := range names {
+		filter.WriteString("(sAMAccountName=" + ldap.EscapeFilter(n) + ")")
+	}
+	filter.WriteString(")")
+
+	sr, err := conn.Search(ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 10, false,
+		filter.String(), []string{"sAMAccountName"}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	taken := make(map[string]bool, len(sr.Entries))
+	for _, e := range sr.Entries {
+		taken[strings.ToLower(e.GetAttributeValue("sAMAccountName"))] = true
+	}
+	return taken, nil
+}
+
+// unusable reports why POST /v1/member would reject name, applying the
+// same username rules, or "" if it would not.
+func (st *clientState) unusable(name string) (reason, detail string) {
+	verr := &ValidationError{}
+	ValidateUsername(name, "username", verr)
+	switch {
+	case verr.ErrOrNil() != nil:
+		return "invalid", verr.Error()
+	case st.usernames.Reserved(name):
+		return "reserved", ""
+	}
+	return "", ""
+}
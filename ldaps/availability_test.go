@@ -0,0 +1,62 @@
+package ldaps
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+var janeDoe = ldap.NewEntry("CN=Jane Doe", map[string][]string{"sAMAccountName": {"JDoe"}})
+
+func TestTakenNames(t *testing.T) {
+	t.Run("one search from the domain root", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, nil)
+		conn := &fakeDirectory{rootDSE: domainRoot, bases: map[string][]*ldap.Entry{"DC=example,DC=local": {janeDoe}}}
+
+		taken, err := c.takenNames(context.Background(), conn, c.current(), []string{"jdoe", "jane.doe", "j*"})
+		is.NoErr(err)
+		is.Equal(taken, map[string]bool{"jdoe": true})
+		is.Equal(len(conn.requests), 2)
+		is.Equal(conn.requests[1].BaseDN, "DC=example,DC=local")
+		is.Equal(conn.requests[1].Filter, `(|(sAMAccountName=jdoe)(sAMAccountName=jane.doe)(sAMAccountName=j\2a))`)
+	})
+
+	t.Run("falls back to BaseDN", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) { cfg.BaseDN = "OU=Members,DC=example,DC=local" })
+		conn := &fakeDirectory{bases: map[string][]*ldap.Entry{"OU=Members,DC=example,DC=local": {janeDoe}}}
+
+		_, err := c.takenNames(context.Background(), conn, c.current(), []string{"jdoe"})
+		is.NoErr(err)
+		is.Equal(conn.requests[1].BaseDN, "OU=Members,DC=example,DC=local")
+	})
+}
+
+func TestUnusable(t *testing.T) {
+	c := testClient(t, nil)
+	tests := []struct {
+		name   string
+		reason string
+		detail string
+	}{
+		{"jdoe", "", ""},
+		{"j", "invalid", "at least 2 characters"},
+		{"j doe", "invalid", "only letters, numbers"},
+		{"jdoe.", "invalid", "must not end with a period"},
+		{"admin", "reserved", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			reason, detail := c.current().unusable(tt.name)
+			is.Equal(reason, tt.reason)
+			is.True(strings.Contains(detail, tt.detail))
+		})
+	}
+}
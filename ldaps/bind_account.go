@@ -0,0 +1,82 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// BindPasswordLastSet returns when the service account's password was last
+// changed, read from its pwdLastSet attribute.
+func (c *Client) BindPasswordLastSet(ctx context.Context) (time.Time, error) {
+	bindDN := c.config().BindDN
+	if bindDN == "" {
+		return time.Time{}, errors.New("no bind DN configured")
+	}
+	conn, err := c.acquire(ctx, roleWrite)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+
+	req := ldap.NewSearchRequest(bindDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(objectClass=*)", []string{"pwdLastSet"}, nil)
+	sr, err := conn.Search(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("read pwdLastSet: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return time.Time{}, fmt.Errorf("bind account %s not found", bindDN)
+	}
+	return parseFileTime(sr.Entries[0].GetAttributeValue("pwdLastSet"))
+}
+
+// ChangeBindPassword changes the service account's own password. It uses
+// AD's user-change form (delete the old unicodePwd, add the new one), which
+// needs no reset privilege but does enforce the password policy.
+func (c *Client) ChangeBindPassword(ctx context.Context, oldPassword, newPassword string) error {
+	st := c.current()
+	lc, err := c.dialAndBindWith(ctx, st, st.write, oldPassword)
+	if err != nil {
+		return err
+	}
+	conn := c.wrap(ctx, lc, nil)
+	defer conn.Close()
+	return c.changeUnicodePwd(conn, st.cfg.BindDN, oldPassword, newPassword)
+}
+
+// VerifyBind dials a fresh connection and binds as the service account with
+// password, bypassing the pool and the configured password source.
+func (c *Client) VerifyBind(ctx context.Context, password string) error {
+	st := c.current()
+	lc, err := c.dialAndBindWith(ctx, st, st.write, password)
+	if err != nil {
+		return err
+	}
+	c.wrap(ctx, lc, nil).Close()
+	return nil
+}
+
+// ResetConnections discards pooled connections and re-reads the password
+// source, so subsequent operations bind with the current secret.
+func (c *Client) ResetConnections() error {
+	return c.Reload(c.config())
+}
+
+// parseFileTime converts an AD FILETIME (100ns intervals since 1601-01-01
+// UTC) to a time.Time.
+func parseFileTime(v string) (time.Time, error) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid FILETIME %q: %w", v, err)
+	}
+	if n == 0 {
+		return time.Time{}, nil
+	}
+	const epochDiff = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100ns
+	return time.Unix(0, (n-epochDiff)*100).UTC(), nil
+}
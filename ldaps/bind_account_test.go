@@ -0,0 +1,23 @@
+package ldaps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestParseFileTime(t *testing.T) {
+	is := is.New(t)
+
+	got, err := parseFileTime("133485408000000000")
+	is.NoErr(err)
+	is.Equal(got, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	got, err = parseFileTime("0")
+	is.NoErr(err)
+	is.True(got.IsZero()) // never set, or must change at next logon
+
+	_, err = parseFileTime("not-a-number")
+	is.True(err != nil)
+}
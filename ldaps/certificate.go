@@ -0,0 +1,170 @@
+package ldaps
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// CertificateInfo describes the earliest-expiring certificate in the chain
+// the directory presented on the most recent handshake.
+type CertificateInfo struct {
+	Subject  string
+	NotAfter time.Time
+}
+
+// PeerCertificate returns the certificate recorded by the most recent TLS
+// handshake with the directory. ok is false before the first handshake and
+// for plaintext transports.
+func (c *Client) PeerCertificate() (info CertificateInfo, ok bool) {
+	if p := c.peerCert.Load(); p != nil {
+		return *p, true
+	}
+	return CertificateInfo{}, false
+}
+
+// recordPeerCertificate captures the chain presented on lc's handshake.
+func (c *Client) recordPeerCertificate(lc *ldap.Conn) {
+	state, ok := lc.TLSConnectionState()
+	if !ok {
+		return
+	}
+	cert := earliestExpiry(state.PeerCertificates)
+	if cert == nil {
+		return
+	}
+	c.peerCert.Store(&CertificateInfo{Subject: cert.Subject.String(), NotAfter: cert.NotAfter})
+	c.instrumentation().CertificateSeen(cert.NotAfter)
+}
+
+func earliestExpiry(chain []*x509.Certificate) *x509.Certificate {
+	var first *x509.Certificate
+	for _, cert := range chain {
+		if first == nil || cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+	return first
+}
+
+// logExpiredCertificate turns an opaque handshake failure caused by an
+// expired certificate into an error log naming the certificate. go-ldap
+// does not wrap handshake errors, so when the certificate cannot be
+// recovered from err the chain is fetched again without verification.
+func (c *Client) logExpiredCertificate(ctx context.Context, st *clientState, err error) {
+	cert := expiredFromError(err)
+	if cert == nil {
+		if !strings.Contains(err.Error(), "certificate has expired") {
+			return
+		}
+		cert = c.expired.get(st.cfg.LdapAddr, time.Now(), func() *x509.Certificate { return c.fetchExpired(st) })
+	}
+	if cert == nil {
+		return
+	}
+	c.log(ctx).Error("ldap.tls.certificate_expired",
+		zap.String("ldap_addr", st.cfg.LdapAddr),
+		zap.String("subject", cert.Subject.String()),
+		zap.Time("not_after", cert.NotAfter),
+	)
+}
+
+func expiredFromError(err error) *x509.Certificate {
+	var le *ldap.Error
+	if errors.As(err, &le) && le.Err != nil {
+		err = le.Err
+	}
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired && invalid.Cert != nil {
+		return invalid.Cert
+	}
+	return nil
+}
+
+// expiredProbeInterval is the minimum time between the unverified
+// handshakes fetchExpired makes for one address, so a pool redialling a DC
+// with an expired certificate does not double its connection attempts.
+const expiredProbeInterval = time.Minute
+
+// expiredProbe rate-limits fetchExpired, reusing the last result in between.
+// The zero value is ready to use.
+type expiredProbe struct {
+	mu   sync.Mutex
+	addr string
+	last time.Time
+	cert *x509.Certificate
+}
+
+// get returns the certificate fetch finds for addr, calling fetch at most
+// once per expiredProbeInterval. Concurrent callers do not wait for a fetch
+// in progress; they get the previous result.
+func (p *expiredProbe) get(addr string, now time.Time, fetch func() *x509.Certificate) *x509.Certificate {
+	p.mu.Lock()
+	if p.addr == addr && now.Sub(p.last) < expiredProbeInterval {
+		cert := p.cert
+		p.mu.Unlock()
+		return cert
+	}
+	if p.addr != addr {
+		p.addr, p.cert = addr, nil
+	}
+	p.last = now
+	p.mu.Unlock()
+
+	cert := fetch()
+	p.mu.Lock()
+	if p.addr == addr {
+		p.cert = cert
+	}
+	p.mu.Unlock()
+	return cert
+}
+
+// fetchExpired handshakes without verification only to read the chain and
+// returns its first expired certificate. It never binds.
+func (c *Client) fetchExpired(st *clientState) *x509.Certificate {
+	tlsCfg := st.tlsConfig.Clone()
+	tlsCfg.InsecureSkipVerify = true
+	tlsCfg.VerifyConnection = nil
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var chain []*x509.Certificate
+	if st.cfg.Transport == config.TransportStartTLS {
+		lc, err := ldap.DialURL(fmt.Sprintf("ldap://%s", st.cfg.LdapAddr), ldap.DialWithDialer(dialer))
+		if err != nil {
+			return nil
+		}
+		defer lc.Close()
+		if err := lc.StartTLS(tlsCfg); err != nil {
+			return nil
+		}
+		state, _ := lc.TLSConnectionState()
+		chain = state.PeerCertificates
+	} else {
+		conn, err := tls.DialWithDialer(dialer, "tcp", st.cfg.LdapAddr, tlsCfg)
+		if err != nil {
+			return nil
+		}
+		defer func() { _ = conn.Close() }()
+		chain = conn.ConnectionState().PeerCertificates
+	}
+
+	now := time.Now()
+	for _, cert := range chain {
+		if now.After(cert.NotAfter) {
+			return cert
+		}
+	}
+	return nil
+}
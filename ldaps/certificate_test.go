@@ -0,0 +1,52 @@
+package ldaps
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+)
+
+func TestEarliestExpiry(t *testing.T) {
+	is := is.New(t)
+	leaf := &x509.Certificate{NotAfter: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	ca := &x509.Certificate{NotAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	is.Equal(earliestExpiry([]*x509.Certificate{ca, leaf}), leaf)
+	is.True(earliestExpiry(nil) == nil)
+}
+
+func TestExpiredFromError(t *testing.T) {
+	is := is.New(t)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "dc01.example.local"}}
+	expired := x509.CertificateInvalidError{Cert: cert, Reason: x509.Expired}
+
+	is.Equal(expiredFromError(ldap.NewError(ldap.ErrorNetwork, expired)), cert)
+	is.True(expiredFromError(ldap.NewError(ldap.ErrorNetwork, x509.CertificateInvalidError{Cert: cert, Reason: x509.NotAuthorizedToSign})) == nil)
+	is.True(expiredFromError(errors.New("connection refused")) == nil)
+}
+
+func TestExpiredProbe(t *testing.T) {
+	is := is.New(t)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "dc01.example.local"}}
+	fetches := 0
+	fetch := func() *x509.Certificate {
+		fetches++
+		return cert
+	}
+	p := &expiredProbe{}
+	now := time.Now()
+
+	is.Equal(p.get("dc01:636", now, fetch), cert)
+	is.Equal(p.get("dc01:636", now.Add(30*time.Second), fetch), cert) // reused
+	is.Equal(fetches, 1)
+
+	p.get("dc02:636", now.Add(30*time.Second), fetch) // another address
+	is.Equal(fetches, 2)
+
+	p.get("dc02:636", now.Add(30*time.Second+expiredProbeInterval), fetch)
+	is.Equal(fetches, 3)
+}
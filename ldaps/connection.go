@@ -5,28 +5,181 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/lugatuic/goberus/config"
+	"github.com/lugatuic/goberus/internal/logctx"
+	"github.com/lugatuic/goberus/internal/passwords"
+	"github.com/lugatuic/goberus/internal/schema"
+	"github.com/lugatuic/goberus/internal/secrets"
+	"github.com/lugatuic/goberus/internal/usernames"
 )
 
-// Client holds configuration and TLS settings for LDAPS connections.
+// Client holds configuration, TLS settings and pooled connections for LDAPS.
+// The configuration generation is swapped atomically by Reload so requests
+// already in flight finish against the state they started with.
 type Client struct {
+	state    atomic.Pointer[clientState]
+	peerCert atomic.Pointer[CertificateInfo]
+	expired  expiredProbe
+	logger   *zap.Logger
+	hooks    Hooks
+	screener PasswordScreener
+}
+
+// clientState is one immutable configuration generation.
+type clientState struct {
 	cfg       *config.Config
 	tlsConfig *tls.Config
-	logger    *zap.Logger
+	schema    *schema.Schema
+	templates map[string]*provisioning
+	limits    *attributeLimits
+	upn       *upnSuffixes
+	generator *passwords.Generator
+	usernames *usernames.Suggester
+	// write binds as BindDN. read binds as ReadBindDN when one is configured
+	// and is otherwise the same identity as write.
+	write *identity
+	read  *identity
+}
+
+// identity is a service account with its own password source and pool, so
+// lookups and probes never hold credentials that can modify the directory.
+type identity struct {
+	dn       string
+	password secrets.Source
+	pool     *pool
+	// external binds with SASL EXTERNAL as the TLS client certificate.
+	external bool
+}
+
+// role selects the identity an operation binds as.
+type role int
+
+const (
+	roleRead role = iota
+	roleWrite
+)
+
+func (st *clientState) identity(r role) *identity {
+	if r == roleWrite {
+		return st.write
+	}
+	return st.read
+}
+
+// close closes the pool of each distinct identity.
+func (st *clientState) close() {
+	st.write.pool.close()
+	if st.read != st.write {
+		st.read.pool.close()
+	}
 }
 
 // NewClient prepares a Client and TLS settings (but does not connect yet).
-func NewClient(cfg *config.Config, logger *zap.Logger) (*Client, error) {
-	c := &Client{cfg: cfg, logger: logger}
+func NewClient(cfg *config.Config, logger *zap.Logger, opts ...Option) (*Client, error) {
+	c := &Client{logger: logger}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	st, err := c.newState(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.state.Store(st)
+	return c, nil
+}
 
+// Reload validates cfg's TLS material and swaps in a new configuration and
+// connection pools. On error the current state is kept. Idle connections of
+// the previous pools are closed; connections in use are closed on release.
+func (c *Client) Reload(cfg *config.Config) error {
+	st, err := c.newState(cfg)
+	if err != nil {
+		return err
+	}
+	if old := c.state.Swap(st); old != nil {
+		old.close()
+	}
+	return nil
+}
+
+// Close releases pooled connections.
+func (c *Client) Close() {
+	if st := c.state.Load(); st != nil {
+		st.close()
+	}
+}
+
+func (c *Client) newState(cfg *config.Config) (*clientState, error) {
+	tlsCfg, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sch, err := schema.New(cfg.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := compileTemplates(cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+	generator, err := passwords.NewGenerator(cfg.PasswordLength, cfg.PasswordClasses, cfg.PasswordWordlist, cfg.PasswordWords)
+	if err != nil {
+		return nil, err
+	}
+	suggester, err := usernames.New(cfg.UsernamePatterns, cfg.ReservedUsernames)
+	if err != nil {
+		return nil, err
+	}
+	st := &clientState{cfg: cfg, tlsConfig: tlsCfg, schema: sch, templates: templates, limits: &attributeLimits{}, upn: &upnSuffixes{}, generator: generator, usernames: suggester}
+	st.write, err = c.newIdentity(st, cfg.BindDN, cfg.BindPassword, cfg.BindPasswordFile, cfg.BindPasswordCommand)
+	if err != nil {
+		return nil, fmt.Errorf("bind password: %w", err)
+	}
+	st.write.external = cfg.BindMechanism == config.BindExternal
+	st.read = st.write
+	if cfg.ReadBindDN != "" {
+		st.read, err = c.newIdentity(st, cfg.ReadBindDN, cfg.ReadBindPassword, cfg.ReadBindPasswordFile, cfg.ReadBindPasswordCommand)
+		if err != nil {
+			return nil, fmt.Errorf("read bind password: %w", err)
+		}
+	}
+	return st, nil
+}
+
+func (c *Client) newIdentity(st *clientState, dn, value, file, command string) (*identity, error) {
+	password, err := secrets.New(value, file, command)
+	if err != nil {
+		return nil, err
+	}
+	id := &identity{dn: dn, password: password}
+	id.pool = newPool(st.cfg.PoolSize, st.cfg.PoolIdleTimeout, c.instrumentation(), func(ctx context.Context) (*ldap.Conn, error) {
+		return c.dialAndBind(ctx, st, id)
+	})
+	return id, nil
+}
+
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
 	tlsCfg := &tls.Config{
 		InsecureSkipVerify: cfg.SkipVerify,
 		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLSServerName,
+	}
+	// StartTLS wraps an existing connection, so unlike ldaps:// dialing
+	// nothing fills in the name to verify.
+	if tlsCfg.ServerName == "" {
+		if host, _, err := net.SplitHostPort(cfg.LdapAddr); err == nil {
+			tlsCfg.ServerName = host
+		}
 	}
 
 	if cfg.CACertPath != "" {
@@ -39,29 +192,174 @@ func NewClient(cfg *config.Config, logger *zap.Logger) (*Client, error) {
 		}
 	}
 
-	c.tlsConfig = tlsCfg
-	return c, nil
+	minV, err := config.TLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if minV != 0 {
+		tlsCfg.MinVersion = minV
+	}
+	if tlsCfg.MaxVersion, err = config.TLSVersion(cfg.TLSMaxVersion); err != nil {
+		return nil, err
+	}
+	if tlsCfg.CipherSuites, err = config.CipherSuites(cfg.TLSCipherSuites); err != nil {
+		return nil, err
+	}
+
+	pins, err := config.SPKIPins(cfg.TLSPinnedSPKI)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) > 0 {
+		tlsCfg.VerifyConnection = config.VerifySPKIPins(pins)
+		// Without a CA the pin alone authenticates the server.
+		if cfg.CACertPath == "" {
+			tlsCfg.InsecureSkipVerify = true
+		}
+	}
+
+	if cfg.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertPath, cfg.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// current returns the active configuration generation.
+func (c *Client) current() *clientState {
+	return c.state.Load()
+}
+
+// config returns the active configuration.
+func (c *Client) config() *config.Config {
+	return c.current().cfg
+}
+
+// log returns the request-scoped logger carried by ctx, falling back to the
+// client's logger for calls made outside an HTTP request.
+func (c *Client) log(ctx context.Context) *zap.Logger {
+	return logctx.From(ctx, c.logger)
+}
+
+// acquire checks a connection bound as r's identity out of the current pool.
+// Closing the returned conn releases it back to the pool it came from.
+func (c *Client) acquire(ctx context.Context, r role) (*conn, error) {
+	id := c.current().identity(r)
+	lc, err := id.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrap(ctx, lc, id.pool), nil
+}
+
+// wrap instruments lc for the request carried by ctx and applies its deadline.
+func (c *Client) wrap(ctx context.Context, lc *ldap.Conn, p *pool) *conn {
+	if dl, ok := ctx.Deadline(); ok {
+		lc.SetTimeout(time.Until(dl))
+	} else {
+		lc.SetTimeout(10 * time.Second)
+	}
+	return &conn{Conn: lc, ctx: ctx, hooks: c.instrumentation(), pool: p}
+}
+
+func (c *Client) dialAndBind(ctx context.Context, st *clientState, id *identity) (*ldap.Conn, error) {
+	return c.dialAndBindWith(ctx, st, id, "")
 }
 
-func (c *Client) dialAndBind(ctx context.Context) (*ldap.Conn, error) {
-	ldapsURL := fmt.Sprintf("ldaps://%s", c.cfg.LdapAddr)
+// dialAndBindWith dials the directory and binds as id. An empty password
+// means id's password source is used.
+func (c *Client) dialAndBindWith(ctx context.Context, st *clientState, id *identity, password string) (_ *ldap.Conn, err error) {
+	cfg := st.cfg
+	ctx, span := tracer.Start(ctx, "ldaps.dialAndBind", trace.WithAttributes(
+		attribute.String("server.address", cfg.LdapAddr),
+		attribute.String("ldap.bind_dn", id.dn),
+	))
+	defer func() {
+		span.SetAttributes(attribute.String("ldap.result_code", resultCode(err)))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	hooks := c.instrumentation()
+	scheme := "ldaps"
+	if cfg.Transport == config.TransportStartTLS || cfg.Transport == config.TransportPlain {
+		scheme = "ldap"
+	}
+	ldapURL := fmt.Sprintf("%s://%s", scheme, cfg.LdapAddr)
 	dialer := &net.Dialer{}
-	conn, err := ldap.DialURL(ldapsURL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(c.tlsConfig))
+	var lc *ldap.Conn
+	err = observe(ctx, hooks, OpDial, "", func() error {
+		var err error
+		lc, err = ldap.DialURL(ldapURL, ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(st.tlsConfig))
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial LDAPS %s: %w", ldapsURL, err)
+		c.logExpiredCertificate(ctx, st, err)
+		return nil, fmt.Errorf("failed to dial %s: %w", ldapURL, err)
 	}
+	hooks.ConnOpened()
 
 	if dl, ok := ctx.Deadline(); ok {
-		conn.SetTimeout(time.Until(dl))
+		lc.SetTimeout(time.Until(dl))
 	} else {
-		conn.SetTimeout(10 * time.Second)
+		lc.SetTimeout(10 * time.Second)
+	}
+
+	if cfg.Transport == config.TransportStartTLS {
+		if err := observe(ctx, hooks, OpStartTLS, "", func() error { return lc.StartTLS(st.tlsConfig) }); err != nil {
+			lc.Close()
+			hooks.ConnClosed()
+			c.logExpiredCertificate(ctx, st, err)
+			return nil, fmt.Errorf("StartTLS with %s failed: %w", cfg.LdapAddr, err)
+		}
 	}
+	c.recordPeerCertificate(lc)
 
-	if c.cfg.BindDN != "" {
-		if bindErr := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); bindErr != nil {
-			conn.Close()
+	if id.external || id.dn != "" {
+		var bindErr error
+		switch {
+		case id.external:
+			bindErr = observe(ctx, hooks, OpBind, id.dn, lc.ExternalBind)
+		case password != "":
+			bindErr = observe(ctx, hooks, OpBind, id.dn, func() error { return lc.Bind(id.dn, password) })
+		default:
+			bindErr = c.bind(ctx, lc, id)
+		}
+		if bindErr != nil {
+			lc.Close()
+			hooks.ConnClosed()
 			return nil, fmt.Errorf("service bind failed: %w", bindErr)
 		}
 	}
-	return conn, nil
+	return lc, nil
+}
+
+// bind authenticates lc as id. When the directory rejects
+// the credential, the password source is re-read and the bind retried once
+// if it changed, so a rotated secret works without a restart and a stale one
+// does not count twice towards account lockout.
+func (c *Client) bind(ctx context.Context, lc *ldap.Conn, id *identity) error {
+	hooks := c.instrumentation()
+	bindDN := id.dn
+	password, err := id.password.Get(ctx)
+	if err != nil {
+		return err
+	}
+	err = observe(ctx, hooks, OpBind, bindDN, func() error { return lc.Bind(bindDN, password) })
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return err
+	}
+
+	id.password.Invalidate()
+	rotated, rerr := id.password.Get(ctx)
+	if rerr != nil || rotated == password {
+		return err
+	}
+	c.log(ctx).Info("ldap.bind.retry_rotated_secret", zap.String("bind_dn", bindDN))
+	return observe(ctx, hooks, OpBind, bindDN, func() error { return lc.Bind(bindDN, rotated) })
 }
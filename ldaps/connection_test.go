@@ -0,0 +1,45 @@
+package ldaps
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Run("defaults to TLS 1.2 with verification", func(t *testing.T) {
+		is := is.New(t)
+		tc, err := newTLSConfig(config.Default())
+		is.NoErr(err)
+		is.Equal(tc.MinVersion, uint16(tls.VersionTLS12))
+		is.True(!tc.InsecureSkipVerify)
+		is.True(tc.VerifyConnection == nil)
+	})
+
+	t.Run("pins replace chain verification without a CA", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.TLSPinnedSPKI = []string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}
+		cfg.TLSServerName = "dc01.example.local"
+		cfg.TLSMinVersion = "1.3"
+
+		tc, err := newTLSConfig(cfg)
+		is.NoErr(err)
+		is.True(tc.InsecureSkipVerify)
+		is.True(tc.VerifyConnection != nil)
+		is.Equal(tc.ServerName, "dc01.example.local")
+		is.Equal(tc.MinVersion, uint16(tls.VersionTLS13))
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		is := is.New(t)
+		cfg := config.Default()
+		cfg.ClientCertPath = "/nonexistent/client.pem"
+		cfg.ClientKeyPath = "/nonexistent/client.key"
+		_, err := newTLSConfig(cfg)
+		is.True(err != nil)
+	})
+}
@@ -1,8 +1,11 @@
 package ldaps
 
 import (
+	"context"
 	"fmt"
 	"strings"
+
+	"github.com/go-ldap/ldap/v3"
 )
 
 func escapeDNComponent(s string) string {
@@ -22,14 +25,125 @@ func escapeDNComponent(s string) string {
 	return builder.String()
 }
 
-func (c *Client) buildUserDN(u *UserInfo) string {
-	escCN := escapeDNComponent(u.Username)
-	ou := strings.TrimSpace(u.OrganizationalUnit)
-	if ou != "" {
-		if strings.HasSuffix(strings.ToLower(ou), strings.ToLower(c.cfg.BaseDN)) {
-			return fmt.Sprintf("CN=%s,%s", escCN, ou)
+// formatDN rebuilds rdns as a DN string, escaping every value, so a parsed
+// DN is written back in one canonical form.
+func formatDN(rdns []*ldap.RelativeDN) string {
+	parts := make([]string, 0, len(rdns))
+	for _, rdn := range rdns {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, a := range rdn.Attributes {
+			attrs = append(attrs, a.Type+"="+escapeDNComponent(a.Value))
+		}
+		parts = append(parts, strings.Join(attrs, "+"))
+	}
+	return strings.Join(parts, ",")
+}
+
+// resolveOU parses ou as a DN at or under BaseDN, or as RDNs relative to
+// it, and returns it in canonical form, or "" for BaseDN itself.
+// Violations are recorded under "ou".
+func (st *clientState) resolveOU(ou string, verr *ValidationError) (string, bool) {
+	base, err := ldap.ParseDN(st.cfg.BaseDN)
+	if err != nil {
+		verr.Add("ou", "base DN %q is not a valid DN: %v", st.cfg.BaseDN, err)
+		return "", false
+	}
+	dn, err := ldap.ParseDN(ou)
+	if err != nil || len(dn.RDNs) == 0 {
+		verr.Add("ou", "%q is not a valid DN", ou)
+		return "", false
+	}
+
+	var rel []*ldap.RelativeDN
+	switch {
+	case dn.EqualFold(base):
+		return "", true
+	case base.AncestorOfFold(dn):
+		rel = dn.RDNs[:len(dn.RDNs)-len(base.RDNs)]
+	default:
+		// A relative OU names containers, never another domain.
+		for _, rdn := range dn.RDNs {
+			for _, a := range rdn.Attributes {
+				if strings.EqualFold(a.Type, "DC") {
+					verr.Add("ou", "%q is not under the base DN %s", ou, st.cfg.BaseDN)
+					return "", false
+				}
+			}
+		}
+		rel = dn.RDNs
+	}
+	return formatDN(append(rel, base.RDNs...)), true
+}
+
+// buildUserDN returns the DN of u's new entry: its username as CN in the
+// requested OU, which must lie under BaseDN. An invalid OU is recorded in
+// verr and BaseDN is used so the rest of the request can still be checked.
+func (c *Client) buildUserDN(st *clientState, u *UserInfo, verr *ValidationError) (dn, ou string) {
+	cn := "CN=" + escapeDNComponent(u.Username)
+	if strings.TrimSpace(u.OrganizationalUnit) != "" {
+		if ou, ok := st.resolveOU(strings.TrimSpace(u.OrganizationalUnit), verr); ok && ou != "" {
+			return cn + "," + ou, ou
+		}
+	}
+	return cn + "," + st.cfg.BaseDN, ""
+}
+
+// allowedOU reports whether ou is listed in AllowedOUs or is the OU of a
+// provisioning template.
+func (st *clientState) allowedOU(ou string) bool {
+	dn, err := ldap.ParseDN(ou)
+	if err != nil {
+		return false
+	}
+	candidates := append([]string{}, st.cfg.AllowedOUs...)
+	for _, t := range st.templates {
+		if t.OU != "" {
+			candidates = append(candidates, t.OU)
+		}
+	}
+	for _, candidate := range candidates {
+		// Template OUs may be relative to BaseDN.
+		resolved, ok := st.resolveOU(candidate, &ValidationError{})
+		if !ok {
+			continue
+		}
+		if resolved == "" {
+			resolved = st.cfg.BaseDN
+		}
+		allowed, err := ldap.ParseDN(resolved)
+		if err == nil && allowed.EqualFold(dn) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOU records a violation when ou may not receive new accounts: with
+// AllowedOUs configured it must be one of them or a template's OU;
+// otherwise it must exist in the directory as an OU or container. An empty
+// ou stands for BaseDN, which only the allowlist can rule out.
+func (c *Client) checkOU(ctx context.Context, conn searcher, st *clientState, ou string, verr *ValidationError) error {
+	if ou == "" {
+		if len(st.cfg.AllowedOUs) == 0 {
+			return nil
 		}
-		return fmt.Sprintf("CN=%s,%s,%s", escCN, ou, c.cfg.BaseDN)
+		ou = st.cfg.BaseDN
+	}
+	if len(st.cfg.AllowedOUs) > 0 {
+		if !st.allowedOU(ou) {
+			verr.Add("ou", "%s is not an allowed OU", ou)
+		}
+		return nil
+	}
+	sr, err := conn.Search(ldap.NewSearchRequest(ou, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(|(objectClass=organizationalUnit)(objectClass=container))", []string{"objectClass"}, nil))
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		verr.Add("ou", "%s does not exist", ou)
+	case err != nil:
+		return fmt.Errorf("ldap OU lookup failed: %w", err)
+	case len(sr.Entries) == 0:
+		verr.Add("ou", "%s is not an organizational unit or container", ou)
 	}
-	return fmt.Sprintf("CN=%s,%s", escCN, c.cfg.BaseDN)
+	return nil
 }
@@ -0,0 +1,119 @@
+package ldaps
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+func TestBuildUserDN(t *testing.T) {
+	tests := []struct {
+		name    string
+		ou      string
+		wantDN  string
+		wantErr string
+	}{
+		{"no OU", "", "CN=j\\,doe,dc=example,dc=local", ""},
+		{"absolute OU keeps case", "OU=ACM Users,DC=Example,DC=Local", "CN=j\\,doe,OU=ACM Users,dc=example,dc=local", ""},
+		{"relative OU", "OU=Students,OU=Members", "CN=j\\,doe,OU=Students,OU=Members,dc=example,dc=local", ""},
+		{"escaped value is re-escaped", `OU=R\+D`, `CN=j\,doe,OU=R\+D,dc=example,dc=local`, ""},
+		{"base DN itself", "dc=example,dc=local", "CN=j\\,doe,dc=example,dc=local", ""},
+		{"other domain", "OU=Users,DC=evil,DC=org", "", "is not under the base DN"},
+		{"not a DN", "Users", "", "is not a valid DN"},
+		{"injected RDN", "OU=x,,DC=evil", "", "is not a valid DN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			c := testClient(t, nil)
+			verr := &ValidationError{}
+			dn, _ := c.buildUserDN(c.current(), &UserInfo{Username: "j,doe", OrganizationalUnit: tt.ou}, verr)
+			if tt.wantErr != "" {
+				is.True(verr.ErrOrNil() != nil)
+				is.Equal(verr.Violations[0].Field, "ou")
+				is.True(strings.Contains(verr.Violations[0].Reason, tt.wantErr))
+				return
+			}
+			is.NoErr(verr.ErrOrNil())
+			is.Equal(dn, tt.wantDN)
+		})
+	}
+}
+
+func TestCheckOU(t *testing.T) {
+	const ou = "OU=Members,dc=example,dc=local"
+
+	t.Run("exists", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		verr := &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), &fakeDirectory{bases: map[string][]*ldap.Entry{ou: {ldap.NewEntry(ou, nil)}}}, c.current(), ou, verr))
+		is.NoErr(verr.ErrOrNil())
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		verr := &ValidationError{}
+		conn := &fakeDirectory{}
+		is.NoErr(c.checkOU(context.Background(), conn, c.current(), ou, verr))
+		is.True(strings.Contains(verr.Error(), "does not exist"))
+	})
+
+	t.Run("not a container", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		verr := &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), &fakeDirectory{bases: map[string][]*ldap.Entry{ou: nil}}, c.current(), ou, verr))
+		is.True(strings.Contains(verr.Error(), "not an organizational unit"))
+	})
+
+	t.Run("allowlist", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		st := c.current()
+		st.cfg.AllowedOUs = []string{"ou=members,DC=example,DC=local"}
+		conn := &fakeDirectory{err: ldap.NewError(ldap.LDAPResultOther, nil)} // never consulted
+
+		verr := &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), conn, st, ou, verr))
+		is.NoErr(verr.ErrOrNil())
+
+		is.NoErr(c.checkOU(context.Background(), conn, st, "OU=Students,dc=example,dc=local", verr)) // template OU
+		is.NoErr(verr.ErrOrNil())
+
+		is.NoErr(c.checkOU(context.Background(), conn, st, "OU=Staff,dc=example,dc=local", verr))
+		is.True(strings.Contains(verr.Error(), "is not an allowed OU"))
+	})
+
+	t.Run("base DN needs the allowlist too", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) { cfg.AllowedOUs = []string{"OU=Members,dc=example,dc=local"} })
+		st := c.current()
+		conn := &fakeDirectory{err: ldap.NewError(ldap.LDAPResultOther, nil)} // never consulted
+
+		verr := &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), conn, st, "", verr))
+		is.Equal(verr.Error(), "ou: dc=example,dc=local is not an allowed OU")
+
+		st.cfg.AllowedOUs = append(st.cfg.AllowedOUs, "DC=Example,DC=Local")
+		verr = &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), conn, st, "", verr))
+		is.NoErr(verr.ErrOrNil())
+	})
+
+	t.Run("base DN without an allowlist", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, nil)
+		conn := &fakeDirectory{}
+		verr := &ValidationError{}
+		is.NoErr(c.checkOU(context.Background(), conn, c.current(), "", verr))
+		is.NoErr(verr.ErrOrNil())
+		is.Equal(len(conn.requests), 0)
+	})
+}
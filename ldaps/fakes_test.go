@@ -0,0 +1,73 @@
+package ldaps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// fakeDirectory answers searches from fixed data: the rootDSE attributes
+// for the empty base and the entries listed under each other base,
+// compared case-insensitively. Unknown bases fail with noSuchObject.
+type fakeDirectory struct {
+	rootDSE  map[string][]string // nil: the rootDSE has no entry
+	bases    map[string][]*ldap.Entry
+	err      error // returned by every search while set
+	requests []*ldap.SearchRequest
+}
+
+func (f *fakeDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if req.BaseDN == "" {
+		if f.rootDSE == nil {
+			return &ldap.SearchResult{}, nil
+		}
+		return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry("", f.rootDSE)}}, nil
+	}
+	for base, entries := range f.bases {
+		if strings.EqualFold(base, req.BaseDN) {
+			return &ldap.SearchResult{Entries: entries}, nil
+		}
+	}
+	return nil, ldap.NewError(ldap.LDAPResultNoSuchObject, nil)
+}
+
+// SearchWithPaging serves the per-attribute scans: only entries holding
+// the first requested attribute match.
+func (f *fakeDirectory) SearchWithPaging(req *ldap.SearchRequest, _ uint32) (*ldap.SearchResult, error) {
+	sr, err := f.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*ldap.Entry
+	for _, e := range sr.Entries {
+		if len(e.GetEqualFoldAttributeValues(req.Attributes[0])) > 0 {
+			matched = append(matched, e)
+		}
+	}
+	return &ldap.SearchResult{Entries: matched}, nil
+}
+
+// domainRoot is the rootDSE of the example.local domain.
+var domainRoot = map[string][]string{"defaultNamingContext": {"DC=example,DC=local"}}
+
+// testClient returns a client for config.Default() as changed by configure,
+// which may be nil.
+func testClient(t *testing.T, configure func(*config.Config)) *Client {
+	t.Helper()
+	cfg := config.Default()
+	if configure != nil {
+		configure(cfg)
+	}
+	c, err := NewClient(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
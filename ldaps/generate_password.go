@@ -0,0 +1,73 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// generateAttempts bounds retries when a generated password happens to fail
+// the policy or screening, which random passwords almost never do.
+const generateAttempts = 20
+
+// GeneratePassword returns a random password for u that satisfies the
+// password policy that will apply to it (see AddUser) and passes screening.
+// When the policy cannot be read, AD's default complexity rules are assumed.
+func (c *Client) GeneratePassword(ctx context.Context, u *UserInfo) (string, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	st := c.current()
+	tmpl, err := st.template(u)
+	if err != nil {
+		return "", err
+	}
+
+	policy := PasswordPolicy{Source: "default", Complexity: true}
+	conn, err := c.acquire(ctxTimeout, roleRead)
+	if err != nil {
+		return "", err
+	}
+	if p, err := c.passwordPolicy(conn, tmpl.Groups); err != nil {
+		c.log(ctx).Warn("ldap.password_policy.unavailable", zap.Error(err))
+	} else {
+		policy = p
+	}
+	conn.Close()
+
+	displayName := u.DisplayName
+	if displayName == "" {
+		displayName = u.GivenName + " " + u.Surname
+	}
+	for i := 0; i < generateAttempts; i++ {
+		password, err := st.generator.Generate(policy.MinLength)
+		if err != nil {
+			return "", err
+		}
+		if len(policy.CheckPassword(password, u.Username, displayName)) > 0 {
+			continue
+		}
+		if err := c.screenPassword(password); errors.Is(err, ErrBreachedPassword) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return password, nil
+	}
+	return "", fmt.Errorf("no generated password satisfied the %s policy after %d attempts", policy.Source, generateAttempts)
+}
+
+// expirePassword sets pwdLastSet to 0 so the password must be changed at
+// the next logon.
+func (c *Client) expirePassword(conn ldapModifier, dn string) error {
+	mr := ldap.NewModifyRequest(dn, nil)
+	mr.Replace("pwdLastSet", []string{"0"})
+	if err := conn.Modify(mr); err != nil {
+		return fmt.Errorf("expire password failed: %w", err)
+	}
+	return nil
+}
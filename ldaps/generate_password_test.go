@@ -0,0 +1,17 @@
+package ldaps
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestExpirePassword(t *testing.T) {
+	is := is.New(t)
+	modifier := &mockModifier{}
+	client := &Client{}
+	is.NoErr(client.expirePassword(modifier, "cn=user"))
+	change := modifier.lastRequest.Changes[0]
+	is.Equal(change.Modification.Type, "pwdLastSet")
+	is.Equal(change.Modification.Vals, []string{"0"})
+}
@@ -0,0 +1,168 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/lugatuic/goberus/ldaps")
+
+// Operation names reported to Hooks.
+const (
+	OpDial     = "dial"
+	OpStartTLS = "starttls"
+	OpBind     = "bind"
+	OpSearch   = "search"
+	OpAdd      = "add"
+	OpModify   = "modify"
+	OpModifyDN = "modifydn"
+	OpDelete   = "delete"
+)
+
+// Hooks receives instrumentation callbacks from the Client so that metrics
+// and similar concerns stay out of the LDAP business code. Implementations
+// must be safe for concurrent use.
+type Hooks interface {
+	// OperationDone is called once per LDAP operation with its result code
+	// ("0" on success, the LDAP result code on failure, or "error" when the
+	// failure did not carry one).
+	OperationDone(op, result string, d time.Duration)
+	// ConnOpened and ConnClosed track connections held open to the directory.
+	ConnOpened()
+	ConnClosed()
+	// CertificateSeen reports when the earliest-expiring certificate in the
+	// chain presented by the directory expires, after each handshake.
+	CertificateSeen(notAfter time.Time)
+}
+
+// Option configures optional Client behavior.
+type Option func(*Client)
+
+// WithHooks registers instrumentation hooks on the Client.
+func WithHooks(h Hooks) Option {
+	return func(c *Client) {
+		c.hooks = h
+	}
+}
+
+type nopHooks struct{}
+
+func (nopHooks) OperationDone(string, string, time.Duration) {}
+func (nopHooks) ConnOpened()                                 {}
+func (nopHooks) ConnClosed()                                 {}
+func (nopHooks) CertificateSeen(time.Time)                   {}
+
+func (c *Client) instrumentation() Hooks {
+	if c == nil || c.hooks == nil {
+		return nopHooks{}
+	}
+	return c.hooks
+}
+
+// observe runs fn as the named operation against dn, recording it as a
+// child span of ctx and reporting its outcome to h.
+func observe(ctx context.Context, h Hooks, op, dn string, fn func() error) error {
+	_, span := tracer.Start(ctx, "ldap."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("ldap.dn", dn)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := fn()
+	code := resultCode(err)
+	h.OperationDone(op, code, time.Since(start))
+
+	span.SetAttributes(attribute.String("ldap.result_code", code))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// resultCode maps an operation error to a low-cardinality label value.
+func resultCode(err error) string {
+	if err == nil {
+		return strconv.Itoa(int(ldap.LDAPResultSuccess))
+	}
+	var lerr *ldap.Error
+	if errors.As(err, &lerr) {
+		return strconv.Itoa(int(lerr.ResultCode))
+	}
+	return "error"
+}
+
+// conn wraps an ldap.Conn so every directory operation is reported to the
+// client's hooks and traced without the callers having to know about them.
+// ctx is the context of the request the connection is checked out for.
+// Close returns the connection to its pool unless an operation failed at
+// the network level.
+type conn struct {
+	*ldap.Conn
+	ctx    context.Context
+	hooks  Hooks
+	pool   *pool
+	broken bool
+}
+
+// track marks the connection unusable after a network-level failure.
+func (c *conn) track(err error) error {
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		c.broken = true
+	}
+	return err
+}
+
+func (c *conn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	var sr *ldap.SearchResult
+	err := observe(c.ctx, c.hooks, OpSearch, req.BaseDN, func() error {
+		var err error
+		sr, err = c.Conn.Search(req)
+		return err
+	})
+	return sr, c.track(err)
+}
+
+func (c *conn) SearchWithPaging(req *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	var sr *ldap.SearchResult
+	err := observe(c.ctx, c.hooks, OpSearch, req.BaseDN, func() error {
+		var err error
+		sr, err = c.Conn.SearchWithPaging(req, pagingSize)
+		return err
+	})
+	return sr, c.track(err)
+}
+
+func (c *conn) Add(req *ldap.AddRequest) error {
+	return c.track(observe(c.ctx, c.hooks, OpAdd, req.DN, func() error { return c.Conn.Add(req) }))
+}
+
+func (c *conn) Modify(req *ldap.ModifyRequest) error {
+	return c.track(observe(c.ctx, c.hooks, OpModify, req.DN, func() error { return c.Conn.Modify(req) }))
+}
+
+func (c *conn) ModifyDN(req *ldap.ModifyDNRequest) error {
+	return c.track(observe(c.ctx, c.hooks, OpModifyDN, req.DN, func() error { return c.Conn.ModifyDN(req) }))
+}
+
+func (c *conn) Del(req *ldap.DelRequest) error {
+	return c.track(observe(c.ctx, c.hooks, OpDelete, req.DN, func() error { return c.Conn.Del(req) }))
+}
+
+func (c *conn) Close() {
+	if c.pool != nil && !c.broken {
+		c.pool.put(c.Conn)
+		return
+	}
+	c.Conn.Close()
+	c.hooks.ConnClosed()
+}
@@ -0,0 +1,43 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+)
+
+type recordingHooks struct {
+	ops []string
+}
+
+func (h *recordingHooks) OperationDone(op, result string, _ time.Duration) {
+	h.ops = append(h.ops, op+":"+result)
+}
+func (h *recordingHooks) ConnOpened()               {}
+func (h *recordingHooks) ConnClosed()               {}
+func (h *recordingHooks) CertificateSeen(time.Time) {}
+
+func TestResultCode(t *testing.T) {
+	is := is.New(t)
+	is.Equal(resultCode(nil), "0")
+	is.Equal(resultCode(ldap.NewError(ldap.LDAPResultEntryAlreadyExists, errors.New("exists"))), "68")
+	is.Equal(resultCode(fmt.Errorf("wrapped: %w", ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("denied")))), "50")
+	is.Equal(resultCode(errors.New("boom")), "error")
+}
+
+func TestObserve(t *testing.T) {
+	is := is.New(t)
+	hooks := &recordingHooks{}
+	wantErr := ldap.NewError(ldap.LDAPResultUnwillingToPerform, errors.New("no"))
+
+	ctx := context.Background()
+
+	is.NoErr(observe(ctx, hooks, OpSearch, "dc=example,dc=local", func() error { return nil }))
+	is.Equal(observe(ctx, hooks, OpModify, "cn=user,dc=example,dc=local", func() error { return wantErr }), wantErr)
+	is.Equal(hooks.ops, []string{"search:0", "modify:53"})
+}
@@ -10,19 +10,19 @@ import (
 	"go.uber.org/zap"
 )
 
-// GetMemberInfo searches for a user by userPrincipalName or sAMAccountName and returns selected attributes.
+// GetMemberInfo searches for a user by userPrincipalName (with any allowed suffix) or sAMAccountName and returns selected attributes.
 func (c *Client) GetMemberInfo(ctx context.Context, username string) (*MemberInfo, error) {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, 8*time.Second)
 	defer cancel()
 
-	conn, err := c.dialAndBind(ctxWithTimeout)
+	st := c.current()
+	conn, err := c.acquire(ctxWithTimeout, roleRead)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Close()
 
-	esc := ldap.EscapeFilter(username)
-	filter := fmt.Sprintf("(|(userPrincipalName=%s)(sAMAccountName=%s))", esc, esc)
+	filter := c.memberFilter(ctx, conn, st, username)
 
 	attributes := []string{
 		"distinguishedName",
@@ -34,9 +34,10 @@ func (c *Client) GetMemberInfo(ctx context.Context, username string) (*MemberInf
 		"description",
 		"badPasswordTime",
 	}
+	attributes = append(attributes, st.schema.Attributes()...)
 
 	searchReq := ldap.NewSearchRequest(
-		c.cfg.BaseDN,
+		st.cfg.BaseDN,
 		ldap.ScopeWholeSubtree,
 		ldap.NeverDerefAliases,
 		1,
@@ -49,9 +50,7 @@ func (c *Client) GetMemberInfo(ctx context.Context, username string) (*MemberInf
 
 	sr, err := conn.Search(searchReq)
 	if err != nil {
-		if c.logger != nil {
-			c.logger.Error("ldap search failed", zap.Error(err), zap.String("filter", filter), zap.String("username", username))
-		}
+		c.log(ctx).Error("ldap search failed", zap.Error(err), zap.String("filter", filter), zap.String("username", username))
 		return nil, fmt.Errorf("ldap search failed: %w", err)
 	}
 
@@ -69,6 +68,7 @@ func (c *Client) GetMemberInfo(ctx context.Context, username string) (*MemberInf
 		SAMAccountName:  entry.GetAttributeValue("sAMAccountName"),
 		Description:     entry.GetAttributeValue("description"),
 		BadPasswordTime: entry.GetAttributeValue("badPasswordTime"),
+		Custom:          st.schema.Decode(entry),
 	}
 
 	members := entry.GetAttributeValues("memberOf")
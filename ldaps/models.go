@@ -11,24 +11,34 @@ type MemberInfo struct {
 	MemberOf        []string `json:"memberOf,omitempty"`
 	Description     string   `json:"description,omitempty"`
 	BadPasswordTime string   `json:"badPasswordTime,omitempty"`
+	// Custom holds the readable fields configured under attributes.
+	Custom map[string]any `json:"custom,omitempty"`
 }
 
 // UserInfo represents the minimal user registration payload used by AddUser.
 type UserInfo struct {
-	Username           string                 `json:"username"`
-	Password           string                 `json:"password"`
-	GivenName          string                 `json:"givenName,omitempty"`
-	Surname            string                 `json:"surname,omitempty"`
-	DisplayName        string                 `json:"displayName,omitempty"`
-	Mail               string                 `json:"mail,omitempty"`
-	Phone              string                 `json:"phone,omitempty"`
-	Description        string                 `json:"description,omitempty"`
-	OrganizationalUnit string                 `json:"ou,omitempty"`
-	CustomAttrs        CustomSchemaAttributes `json:"custom,omitempty"`
-}
-
-// CustomSchemaAttributes captures application-defined AD attributes that are not in the default schema.
-type CustomSchemaAttributes struct {
-	Major   string `json:"major,omitempty"`
-	College string `json:"college,omitempty"`
+	Username           string `json:"username"`
+	Password           string `json:"password"`
+	GivenName          string `json:"givenName,omitempty"`
+	Surname            string `json:"surname,omitempty"`
+	DisplayName        string `json:"displayName,omitempty"`
+	Mail               string `json:"mail,omitempty"`
+	Phone              string `json:"phone,omitempty"`
+	Description        string `json:"description,omitempty"`
+	OrganizationalUnit string `json:"ou,omitempty"`
+	// GeneratePassword asks for a generated password, returned once in the
+	// response, instead of Password.
+	GeneratePassword bool `json:"generatePassword,omitempty"`
+	// MustChangePassword expires the password so it must be changed at the
+	// next logon.
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
+	// UPNSuffix chooses the userPrincipalName suffix among the allowed ones;
+	// empty selects the configured default.
+	UPNSuffix string `json:"upnSuffix,omitempty"`
+	// Template names the provisioning template to apply; empty selects the
+	// configured default.
+	Template string `json:"template,omitempty"`
+	// CustomAttrs holds values for the fields configured under attributes,
+	// keyed by API field name.
+	CustomAttrs map[string]any `json:"custom,omitempty"`
 }
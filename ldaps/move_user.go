@@ -0,0 +1,187 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// ErrNoSuchMember is returned when the account to change does not exist.
+var ErrNoSuchMember = errors.New("no such member")
+
+// MoveRequest renames a member, moves it to another OU, or both.
+type MoveRequest struct {
+	// Username is the member's current sAMAccountName.
+	Username string `json:"username"`
+	// NewUsername becomes the sAMAccountName, CN and UPN prefix.
+	NewUsername string `json:"newUsername,omitempty"`
+	// OrganizationalUnit is the new container, validated as for AddUser.
+	OrganizationalUnit string `json:"ou,omitempty"`
+	DisplayName        string `json:"displayName,omitempty"`
+	// UPNSuffix replaces the UPN suffix; empty keeps the current one.
+	UPNSuffix string `json:"upnSuffix,omitempty"`
+}
+
+// movedUPN returns the userPrincipalName after a move. It is only derived
+// when the account is renamed, taking the new name as prefix, or given a
+// new suffix, keeping the old prefix; otherwise the current UPN, possibly
+// one that does not follow the sAMAccountName or none at all, is kept.
+func movedUPN(st *clientState, oldUPN, oldName, newName string, renamed bool, newSuffix string) string {
+	if !renamed && newSuffix == "" {
+		return oldUPN
+	}
+	prefix, suffix, ok := strings.Cut(oldUPN, "@")
+	if !ok {
+		prefix, suffix = oldName, st.upnSuffix(&UserInfo{})
+	}
+	if renamed {
+		prefix = newName
+	}
+	if newSuffix != "" {
+		suffix = newSuffix
+	}
+	return prefix + "@" + suffix
+}
+
+type mover interface {
+	searcher
+	ldapModifier
+	ModifyDN(*ldap.ModifyDNRequest) error
+}
+
+// MoveUser renames or moves a member in place with ModifyDN, so its SID,
+// group memberships and history are kept, and updates sAMAccountName,
+// userPrincipalName and displayName to match. It returns the new DN.
+func (c *Client) MoveUser(ctx context.Context, m *MoveRequest) (string, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	st := c.current()
+	conn, err := c.acquire(ctxTimeout, roleWrite)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return c.moveUser(ctx, conn, st, m)
+}
+
+func (c *Client) moveUser(ctx context.Context, conn mover, st *clientState, m *MoveRequest) (string, error) {
+	logger := c.log(ctx)
+	sr, err := conn.Search(ldap.NewSearchRequest(st.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 10, false,
+		"(&"+userFilter+"(sAMAccountName="+ldap.EscapeFilter(m.Username)+"))",
+		[]string{"sAMAccountName", "userPrincipalName", "displayName"}, nil))
+	if err != nil {
+		return "", fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoSuchMember, m.Username)
+	}
+	entry := sr.Entries[0]
+	current, err := ldap.ParseDN(entry.DN)
+	if err != nil || len(current.RDNs) < 2 {
+		return "", fmt.Errorf("member %s has unexpected DN %q", m.Username, entry.DN)
+	}
+	oldName := entry.GetAttributeValue("sAMAccountName")
+	oldUPN := entry.GetAttributeValue("userPrincipalName")
+	oldDisplayName := entry.GetAttributeValue("displayName")
+
+	verr := &ValidationError{}
+	newName := oldName
+	renamed := m.NewUsername != "" && !strings.EqualFold(m.NewUsername, oldName)
+	if renamed {
+		newName = m.NewUsername
+		ValidateUsername(newName, "newUsername", verr)
+		if st.usernames.Reserved(newName) {
+			verr.Add("newUsername", "%q is reserved", newName)
+		}
+	}
+	superior := formatDN(current.RDNs[1:])
+	if m.OrganizationalUnit != "" {
+		if ou, ok := st.resolveOU(m.OrganizationalUnit, verr); ok {
+			superior = st.cfg.BaseDN
+			if ou != "" {
+				superior = ou
+			}
+			if err := c.checkOU(ctx, conn, st, ou, verr); err != nil {
+				return "", err
+			}
+		}
+	}
+	c.checkUPNSuffix(ctx, conn, st, &UserInfo{UPNSuffix: m.UPNSuffix}, verr)
+	if err := verr.ErrOrNil(); err != nil {
+		return "", err
+	}
+
+	upn := movedUPN(st, oldUPN, oldName, newName, renamed, m.UPNSuffix)
+	if !strings.EqualFold(upn, oldUPN) {
+		probe := ldap.NewAddRequest("", nil)
+		probe.Attribute("userPrincipalName", []string{upn})
+		if err := c.checkUnique(ctx, conn, st, probe); err != nil {
+			return "", err
+		}
+	}
+
+	mr := ldap.NewModifyRequest(entry.DN, nil)
+	undo := ldap.NewModifyRequest(entry.DN, nil)
+	replace := func(attr, from, to string) {
+		if to == "" || to == from {
+			return
+		}
+		mr.Replace(attr, []string{to})
+		if from == "" {
+			undo.Delete(attr, nil)
+		} else {
+			undo.Replace(attr, []string{from})
+		}
+	}
+	replace("sAMAccountName", oldName, newName)
+	replace("userPrincipalName", oldUPN, upn)
+	displayName := m.DisplayName
+	if displayName == "" && renamed && strings.EqualFold(oldDisplayName, oldName) {
+		displayName = newName // a display name that was just the username follows it
+	}
+	replace("displayName", oldDisplayName, displayName)
+	if len(mr.Changes) > 0 {
+		if err := conn.Modify(mr); err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+				return "", c.addConflict(ctx, conn, st, entry.DN, newName)
+			}
+			return "", fmt.Errorf("ldap modify failed: %w", err)
+		}
+	}
+
+	rdn := formatDN(current.RDNs[:1])
+	if renamed {
+		rdn = "CN=" + escapeDNComponent(newName)
+	}
+	newDN := rdn + "," + superior
+	if target, err := ldap.ParseDN(newDN); err == nil && target.EqualFold(current) {
+		logger.Info("user updated", zap.String("dn", entry.DN), zap.String("username", newName))
+		return entry.DN, nil
+	}
+
+	newSuperior := superior
+	if parent, err := ldap.ParseDN(superior); err == nil && parent.EqualFold(&ldap.DN{RDNs: current.RDNs[1:]}) {
+		newSuperior = "" // rename in place
+	}
+	if err := conn.ModifyDN(ldap.NewModifyDNRequest(entry.DN, rdn, true, newSuperior)); err != nil {
+		if len(undo.Changes) > 0 {
+			if uerr := conn.Modify(undo); uerr != nil {
+				logger.Error("revert after failed move failed", zap.Error(uerr), zap.String("dn", entry.DN))
+			}
+		}
+		logger.Error("ldap modify dn failed", zap.Error(err), zap.String("dn", entry.DN), zap.String("new_dn", newDN))
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			return "", &ConflictError{Attribute: "distinguishedName", Value: newDN, DN: newDN}
+		}
+		return "", fmt.Errorf("ldap modify dn failed: %w", err)
+	}
+
+	logger.Info("user moved", zap.String("dn", entry.DN), zap.String("new_dn", newDN), zap.String("username", newName))
+	return newDN, nil
+}
@@ -0,0 +1,195 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// fakeMover holds one member and records the changes made to it.
+type fakeMover struct {
+	member      *ldap.Entry
+	modifies    []*ldap.ModifyRequest
+	modifyDNs   []*ldap.ModifyDNRequest
+	modifyDNErr error
+}
+
+func (f *fakeMover) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	switch {
+	case req.BaseDN == "":
+		return &ldap.SearchResult{}, nil // no rootDSE: configured suffixes only
+	case req.Scope == ldap.ScopeBaseObject: // OU check
+		return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry(req.BaseDN, nil)}}, nil
+	case f.member != nil && strings.Contains(req.Filter, "(sAMAccountName=jdoe)"):
+		return &ldap.SearchResult{Entries: []*ldap.Entry{f.member}}, nil
+	}
+	return &ldap.SearchResult{}, nil
+}
+
+func (f *fakeMover) Modify(req *ldap.ModifyRequest) error {
+	f.modifies = append(f.modifies, req)
+	return nil
+}
+
+func (f *fakeMover) ModifyDN(req *ldap.ModifyDNRequest) error {
+	f.modifyDNs = append(f.modifyDNs, req)
+	return f.modifyDNErr
+}
+
+// newFakeMover holds jdoe with the given UPN, or none when upn is empty.
+func newFakeMover(upn string) *fakeMover {
+	attrs := map[string][]string{
+		"sAMAccountName": {"jdoe"},
+		"displayName":    {"Jane Doe"},
+	}
+	if upn != "" {
+		attrs["userPrincipalName"] = []string{upn}
+	}
+	return &fakeMover{member: ldap.NewEntry("CN=jdoe,OU=Students,dc=example,dc=local", attrs)}
+}
+
+// withoutUniqueness leaves the UPN uniqueness search out of the requests.
+func withoutUniqueness(cfg *config.Config) { cfg.UniqueAttributes = "" }
+
+func changes(mr *ldap.ModifyRequest) map[string][]string {
+	out := make(map[string][]string)
+	for _, c := range mr.Changes {
+		out[c.Modification.Type] = c.Modification.Vals
+	}
+	return out
+}
+
+func TestMoveUser(t *testing.T) {
+	t.Run("rename and move", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jdoe@example.local")
+
+		dn, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", NewUsername: "jsmith", OrganizationalUnit: "OU=Alumni", DisplayName: "Jane Smith"})
+		is.NoErr(err)
+		is.Equal(dn, "CN=jsmith,OU=Alumni,dc=example,dc=local")
+		is.Equal(changes(conn.modifies[0]), map[string][]string{
+			"sAMAccountName":    {"jsmith"},
+			"userPrincipalName": {"jsmith@example.local"},
+			"displayName":       {"Jane Smith"},
+		})
+		is.Equal(*conn.modifyDNs[0], *ldap.NewModifyDNRequest("CN=jdoe,OU=Students,dc=example,dc=local", "CN=jsmith", true, "OU=Alumni,dc=example,dc=local"))
+	})
+
+	t.Run("rename in place", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jdoe@example.local")
+
+		dn, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", NewUsername: "jsmith"})
+		is.NoErr(err)
+		is.Equal(dn, "CN=jsmith,OU=Students,dc=example,dc=local")
+		is.Equal(conn.modifyDNs[0].NewSuperior, "")
+		is.Equal(changes(conn.modifies[0])["displayName"], nil) // not just the username, so kept
+	})
+
+	t.Run("move only keeps the name", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jdoe@example.local")
+
+		dn, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", OrganizationalUnit: "OU=Alumni,DC=Example,DC=Local"})
+		is.NoErr(err)
+		is.Equal(dn, "CN=jdoe,OU=Alumni,dc=example,dc=local")
+		is.Equal(len(conn.modifies), 0)
+		is.Equal(conn.modifyDNs[0].NewRDN, "CN=jdoe")
+	})
+
+	t.Run("move only keeps a UPN that does not follow the username", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jane.doe@example.local")
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", OrganizationalUnit: "OU=Alumni"})
+		is.NoErr(err)
+		is.Equal(len(conn.modifies), 0)
+	})
+
+	t.Run("move only adds no UPN", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("")
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", OrganizationalUnit: "OU=Alumni"})
+		is.NoErr(err)
+		is.Equal(len(conn.modifies), 0)
+	})
+
+	t.Run("new suffix keeps the UPN prefix", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		c.current().cfg.UPNSuffixes = []string{"alumni.example"}
+		conn := newFakeMover("jane.doe@example.local")
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", UPNSuffix: "alumni.example"})
+		is.NoErr(err)
+		is.Equal(changes(conn.modifies[0]), map[string][]string{"userPrincipalName": {"jane.doe@alumni.example"}})
+		is.Equal(len(conn.modifyDNs), 0)
+	})
+
+	t.Run("failed move reverts the attributes", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jdoe@example.local")
+		conn.modifyDNErr = ldap.NewError(ldap.LDAPResultEntryAlreadyExists, errors.New("exists"))
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", NewUsername: "jsmith"})
+		var conflict *ConflictError
+		is.True(errors.As(err, &conflict))
+		is.Equal(len(conn.modifies), 2)
+		is.Equal(changes(conn.modifies[1]), map[string][]string{
+			"sAMAccountName":    {"jdoe"},
+			"userPrincipalName": {"jdoe@example.local"},
+		})
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withoutUniqueness)
+		conn := newFakeMover("jdoe@example.local")
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", NewUsername: "admin", OrganizationalUnit: "OU=x,DC=evil,DC=org", UPNSuffix: "evil.org"})
+		var verr *ValidationError
+		is.True(errors.As(err, &verr))
+		var fields []string
+		for _, v := range verr.Violations {
+			fields = append(fields, v.Field)
+		}
+		is.Equal(fields, []string{"newUsername", "ou", "upnSuffix"})
+		is.Equal(len(conn.modifies), 0)
+		is.Equal(len(conn.modifyDNs), 0)
+	})
+
+	t.Run("base DN outside the allowlist", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) {
+			withoutUniqueness(cfg)
+			cfg.AllowedOUs = []string{"OU=Alumni,dc=example,dc=local"}
+		})
+		conn := newFakeMover("jdoe@example.local")
+
+		_, err := c.moveUser(context.Background(), conn, c.current(), &MoveRequest{Username: "jdoe", OrganizationalUnit: "dc=example,dc=local"})
+		var verr *ValidationError
+		is.True(errors.As(err, &verr))
+		is.Equal(verr.Error(), "ou: dc=example,dc=local is not an allowed OU")
+		is.Equal(len(conn.modifyDNs), 0)
+	})
+
+	t.Run("unknown member", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, nil)
+		_, err := c.moveUser(context.Background(), &fakeMover{}, c.current(), &MoveRequest{Username: "jdoe", OrganizationalUnit: "OU=Alumni"})
+		is.True(errors.Is(err, ErrNoSuchMember))
+	})
+}
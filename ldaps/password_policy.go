@@ -0,0 +1,212 @@
+package ldaps
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// pwdProperties flag requiring complex passwords.
+const domainPasswordComplex = 0x1
+
+// PasswordPolicy is the password policy that applies to a new account.
+type PasswordPolicy struct {
+	// Source is "domain" or the DN of the fine-grained policy (PSO).
+	Source     string
+	MinLength  int
+	Complexity bool
+	// HistoryLength is reported for completeness; a new account has no
+	// history to compare against.
+	HistoryLength int
+}
+
+// PasswordPolicyError lists the rules of Policy the submitted password does
+// not meet. It describes a client mistake (422).
+type PasswordPolicyError struct {
+	Policy string
+	Unmet  []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("password does not meet the %s policy: %s", e.Policy, strings.Join(e.Unmet, "; "))
+}
+
+// CheckPassword evaluates password as Active Directory would for an account
+// with the given sAMAccountName and displayName. It returns the unmet rules.
+func (p PasswordPolicy) CheckPassword(password, samAccountName, displayName string) []string {
+	var unmet []string
+	if n := len([]rune(password)); n < p.MinLength {
+		unmet = append(unmet, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if !p.Complexity {
+		return unmet
+	}
+
+	if n := passwordCategories(password); n < 3 {
+		unmet = append(unmet, "must contain characters from three of: uppercase letters, lowercase letters, digits, symbols, other letters")
+	}
+	lower := strings.ToLower(password)
+	// AD ignores account names shorter than three characters.
+	if len(samAccountName) >= 3 && strings.Contains(lower, strings.ToLower(samAccountName)) {
+		unmet = append(unmet, "must not contain the account name")
+	}
+	for _, token := range displayNameTokens(displayName) {
+		if strings.Contains(lower, strings.ToLower(token)) {
+			unmet = append(unmet, fmt.Sprintf("must not contain %q from the display name", token))
+		}
+	}
+	return unmet
+}
+
+func passwordCategories(password string) int {
+	var upper, lower, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsLetter(r):
+			other = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{upper, lower, digit, symbol, other} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// displayNameTokens splits name on the delimiters AD uses and keeps tokens
+// of three or more characters.
+func displayNameTokens(name string) []string {
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return strings.ContainsRune(",.-_#\t ", r)
+	})
+	var tokens []string
+	for _, f := range fields {
+		if len([]rune(f)) >= 3 {
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+// passwordPolicy reads the policy for an account that will join groups: the
+// fine-grained policy with the lowest precedence applying to one of the
+// groups, otherwise the domain policy.
+func (c *Client) passwordPolicy(conn searcher, groups []string) (PasswordPolicy, error) {
+	domainDN, err := readRootDSE(conn, "defaultNamingContext")
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+
+	if len(groups) > 0 {
+		pso, found, err := readPSO(conn, domainDN, groups)
+		if err != nil {
+			return PasswordPolicy{}, err
+		}
+		if found {
+			return pso, nil
+		}
+	}
+
+	sr, err := conn.Search(ldap.NewSearchRequest(domainDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(objectClass=*)", []string{"minPwdLength", "pwdProperties", "pwdHistoryLength"}, nil))
+	if err != nil {
+		return PasswordPolicy{}, fmt.Errorf("read domain password policy: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return PasswordPolicy{}, fmt.Errorf("domain object %s not found", domainDN)
+	}
+	e := sr.Entries[0]
+	props := atoi(e.GetAttributeValue("pwdProperties"))
+	return PasswordPolicy{
+		Source:        "domain",
+		MinLength:     atoi(e.GetAttributeValue("minPwdLength")),
+		Complexity:    props&domainPasswordComplex != 0,
+		HistoryLength: atoi(e.GetAttributeValue("pwdHistoryLength")),
+	}, nil
+}
+
+// readPSO returns the winning password settings object applied to any of
+// groups. PSOs apply to users and global groups, not OUs; an OU is covered
+// through a shadow group listed in the provisioning template.
+func readPSO(conn searcher, domainDN string, groups []string) (PasswordPolicy, bool, error) {
+	var filter strings.Builder
+	filter.WriteString("(&(objectClass=msDS-PasswordSettings)(|")
+	for _, g := range groups {
+		filter.WriteString("(msDS-PSOAppliesTo=" + ldap.EscapeFilter(g) + ")")
+	}
+	filter.WriteString("))")
+
+	sr, err := conn.Search(ldap.NewSearchRequest("CN=Password Settings Container,CN=System,"+domainDN,
+		ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 0, 10, false, filter.String(),
+		[]string{"msDS-PasswordSettingsPrecedence", "msDS-MinimumPasswordLength", "msDS-PasswordComplexityEnabled", "msDS-PasswordHistoryLength", "objectGUID"}, nil))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return PasswordPolicy{}, false, nil
+	}
+	if err != nil {
+		return PasswordPolicy{}, false, fmt.Errorf("read password settings objects: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return PasswordPolicy{}, false, nil
+	}
+
+	// Like AD, break precedence ties by the lowest objectGUID.
+	entries := sr.Entries
+	sort.Slice(entries, func(i, j int) bool {
+		pi := atoi(entries[i].GetAttributeValue("msDS-PasswordSettingsPrecedence"))
+		pj := atoi(entries[j].GetAttributeValue("msDS-PasswordSettingsPrecedence"))
+		if pi != pj {
+			return pi < pj
+		}
+		return bytes.Compare(entries[i].GetRawAttributeValue("objectGUID"), entries[j].GetRawAttributeValue("objectGUID")) < 0
+	})
+	e := entries[0]
+	return PasswordPolicy{
+		Source:        e.DN,
+		MinLength:     atoi(e.GetAttributeValue("msDS-MinimumPasswordLength")),
+		Complexity:    strings.EqualFold(e.GetAttributeValue("msDS-PasswordComplexityEnabled"), "TRUE"),
+		HistoryLength: atoi(e.GetAttributeValue("msDS-PasswordHistoryLength")),
+	}, true, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// checkPasswordPolicy evaluates u's password before the account is created.
+// When the policy cannot be read the check is skipped and AD still enforces
+// it when the password is set.
+func (c *Client) checkPasswordPolicy(ctx context.Context, conn searcher, tmpl *provisioning, req *ldap.AddRequest, u *UserInfo) error {
+	policy, err := c.passwordPolicy(conn, tmpl.Groups)
+	if err != nil {
+		c.log(ctx).Warn("ldap.password_policy.unavailable", zap.Error(err))
+		return nil
+	}
+	var displayName string
+	for _, a := range req.Attributes {
+		if strings.EqualFold(a.Type, "displayName") && len(a.Vals) > 0 {
+			displayName = a.Vals[0]
+		}
+	}
+	if unmet := policy.CheckPassword(u.Password, u.Username, displayName); len(unmet) > 0 {
+		return &PasswordPolicyError{Policy: policy.Source, Unmet: unmet}
+	}
+	return nil
+}
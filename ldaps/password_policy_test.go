@@ -0,0 +1,98 @@
+package ldaps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+)
+
+func TestCheckPassword(t *testing.T) {
+	policy := PasswordPolicy{Source: "domain", MinLength: 8, Complexity: true}
+	tests := []struct {
+		name     string
+		password string
+		unmet    []string
+	}{
+		{"meets policy", "Tr0ub4dor&3", nil},
+		{"too short", "Ab1!", []string{"at least 8"}},
+		{"two categories", "lowercase123", []string{"three of"}},
+		{"contains account name", "xJDoe-2024!", []string{"account name"}},
+		{"contains display name token", "Smithers#2024", []string{`"Smithers"`}},
+		{"caseless letters count as a category", "漢字漢字123abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			unmet := policy.CheckPassword(tt.password, "jdoe", "Jane Smithers-Ng")
+			is.Equal(len(unmet), len(tt.unmet))
+			for i, want := range tt.unmet {
+				is.True(strings.Contains(unmet[i], want))
+			}
+		})
+	}
+
+	t.Run("no complexity only checks length", func(t *testing.T) {
+		is := is.New(t)
+		is.Equal(len(PasswordPolicy{MinLength: 4}.CheckPassword("jdoejdoe", "jdoe", "")), 0)
+	})
+}
+
+// policyDirectory holds the domain policy and psos.
+func policyDirectory(psos ...*ldap.Entry) *fakeDirectory {
+	return &fakeDirectory{rootDSE: domainRoot, bases: map[string][]*ldap.Entry{
+		"DC=example,DC=local": {
+			ldap.NewEntry("DC=example,DC=local", map[string][]string{"minPwdLength": {"7"}, "pwdProperties": {"1"}, "pwdHistoryLength": {"24"}}),
+		},
+		"CN=Password Settings Container,CN=System,DC=example,DC=local": psos,
+	}}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Run("domain policy", func(t *testing.T) {
+		is := is.New(t)
+		c := &Client{}
+		policy, err := c.passwordPolicy(policyDirectory(), nil)
+		is.NoErr(err)
+		is.Equal(policy, PasswordPolicy{Source: "domain", MinLength: 7, Complexity: true, HistoryLength: 24})
+	})
+
+	t.Run("lowest precedence PSO wins", func(t *testing.T) {
+		is := is.New(t)
+		c := &Client{}
+		conn := policyDirectory(
+			ldap.NewEntry("CN=Loose", map[string][]string{"msDS-PasswordSettingsPrecedence": {"20"}, "msDS-MinimumPasswordLength": {"6"}}),
+			ldap.NewEntry("CN=Strict", map[string][]string{
+				"msDS-PasswordSettingsPrecedence": {"10"},
+				"msDS-MinimumPasswordLength":      {"14"},
+				"msDS-PasswordComplexityEnabled":  {"TRUE"},
+			}),
+		)
+		policy, err := c.passwordPolicy(conn, []string{"CN=Members,DC=example,DC=local"})
+		is.NoErr(err)
+		is.Equal(policy.Source, "CN=Strict")
+		is.Equal(policy.MinLength, 14)
+		is.True(policy.Complexity)
+	})
+
+	t.Run("precedence ties go to the lowest objectGUID", func(t *testing.T) {
+		is := is.New(t)
+		c := &Client{}
+		conn := policyDirectory(
+			ldap.NewEntry("CN=High", map[string][]string{"msDS-PasswordSettingsPrecedence": {"10"}, "objectGUID": {"\x9a\x01"}}),
+			ldap.NewEntry("CN=Low", map[string][]string{"msDS-PasswordSettingsPrecedence": {"10"}, "objectGUID": {"\x1b\xff"}}),
+		)
+		policy, err := c.passwordPolicy(conn, []string{"CN=Members,DC=example,DC=local"})
+		is.NoErr(err)
+		is.Equal(policy.Source, "CN=Low")
+	})
+
+	t.Run("domain policy when no PSO applies", func(t *testing.T) {
+		is := is.New(t)
+		c := &Client{}
+		policy, err := c.passwordPolicy(policyDirectory(), []string{"CN=Members,DC=example,DC=local"})
+		is.NoErr(err)
+		is.Equal(policy.Source, "domain")
+	})
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 )
 
-// Ping checks the LDAP dependency is reachable and bind works.
+// Ping checks the LDAP dependency is reachable and the read identity can
+// bind. It always dials a fresh connection so a healthy pool cannot mask an
+// unreachable DC.
 func (c *Client) Ping(ctx context.Context) error {
-	conn, err := c.dialAndBind(ctx)
+	st := c.current()
+	lc, err := c.dialAndBind(ctx, st, st.read)
 	if err != nil {
 		return err
 	}
-	conn.Close()
+	c.wrap(ctx, lc, nil).Close()
 	return nil
 }
@@ -0,0 +1,95 @@
+package ldaps
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// pool keeps bound connections for reuse so each request does not pay for a
+// TLS handshake and bind. A pool belongs to one configuration generation:
+// Reload closes the old pool and connections still checked out from it are
+// closed when they are released instead of being returned.
+type pool struct {
+	dial        func(context.Context) (*ldap.Conn, error)
+	hooks       Hooks
+	idleTimeout time.Duration
+
+	mu     sync.Mutex
+	idle   chan idleConn
+	closed bool
+}
+
+type idleConn struct {
+	conn  *ldap.Conn
+	since time.Time
+}
+
+func newPool(size int, idleTimeout time.Duration, hooks Hooks, dial func(context.Context) (*ldap.Conn, error)) *pool {
+	if size < 0 {
+		size = 0
+	}
+	return &pool{
+		dial:        dial,
+		hooks:       hooks,
+		idleTimeout: idleTimeout,
+		idle:        make(chan idleConn, size),
+	}
+}
+
+// get returns an idle connection that is still usable, or dials a new one.
+func (p *pool) get(ctx context.Context) (*ldap.Conn, error) {
+	for {
+		select {
+		case ic := <-p.idle:
+			if ic.conn.IsClosing() || (p.idleTimeout > 0 && time.Since(ic.since) > p.idleTimeout) {
+				p.discard(ic.conn)
+				continue
+			}
+			return ic.conn, nil
+		default:
+			return p.dial(ctx)
+		}
+	}
+}
+
+// put returns lc to the pool, closing it if the pool is full or closed.
+func (p *pool) put(lc *ldap.Conn) {
+	if lc.IsClosing() {
+		p.discard(lc)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.discard(lc)
+		return
+	}
+	select {
+	case p.idle <- idleConn{conn: lc, since: time.Now()}:
+	default:
+		p.discard(lc)
+	}
+}
+
+// close stops reuse and closes every idle connection.
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for {
+		select {
+		case ic := <-p.idle:
+			p.discard(ic.conn)
+		default:
+			return
+		}
+	}
+}
+
+func (p *pool) discard(lc *ldap.Conn) {
+	lc.Close()
+	p.hooks.ConnClosed()
+}
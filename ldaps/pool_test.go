@@ -0,0 +1,134 @@
+package ldaps
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// pipeConn returns a started ldap.Conn over an in-memory pipe.
+func pipeConn(t *testing.T) *ldap.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = server.Close() })
+	lc := ldap.NewConn(client, false)
+	lc.Start()
+	return lc
+}
+
+func TestPool(t *testing.T) {
+	t.Run("reuses released connections", func(t *testing.T) {
+		is := is.New(t)
+		dials := 0
+		p := newPool(2, time.Minute, nopHooks{}, func(context.Context) (*ldap.Conn, error) {
+			dials++
+			return pipeConn(t), nil
+		})
+
+		first, err := p.get(context.Background())
+		is.NoErr(err)
+		p.put(first)
+		second, err := p.get(context.Background())
+		is.NoErr(err)
+
+		is.Equal(second, first)
+		is.Equal(dials, 1)
+	})
+
+	t.Run("drops expired and closed connections", func(t *testing.T) {
+		is := is.New(t)
+		dials := 0
+		p := newPool(2, time.Nanosecond, nopHooks{}, func(context.Context) (*ldap.Conn, error) {
+			dials++
+			return pipeConn(t), nil
+		})
+
+		lc, err := p.get(context.Background())
+		is.NoErr(err)
+		p.put(lc)
+		time.Sleep(time.Millisecond)
+		_, err = p.get(context.Background())
+		is.NoErr(err)
+
+		is.Equal(dials, 2)
+		is.True(lc.IsClosing())
+	})
+
+	t.Run("close discards idle and later releases", func(t *testing.T) {
+		is := is.New(t)
+		p := newPool(2, time.Minute, nopHooks{}, func(context.Context) (*ldap.Conn, error) {
+			return pipeConn(t), nil
+		})
+
+		idle, err := p.get(context.Background())
+		is.NoErr(err)
+		inUse, err := p.get(context.Background())
+		is.NoErr(err)
+		p.put(idle)
+
+		p.close()
+		is.True(idle.IsClosing())
+
+		p.put(inUse)
+		is.True(inUse.IsClosing())
+		is.Equal(len(p.idle), 0)
+	})
+}
+
+func TestReload(t *testing.T) {
+	is := is.New(t)
+	cfg := &config.Config{LdapAddr: "dc1.example.local:636", BaseDN: "dc=example,dc=local", PoolSize: 1}
+	c, err := NewClient(cfg, nil)
+	is.NoErr(err)
+	first := c.current()
+
+	bad := *cfg
+	bad.CACertPath = "/nonexistent/ca.pem"
+	is.True(c.Reload(&bad) != nil)
+	is.Equal(c.current(), first) // invalid config keeps the running state
+
+	next := *cfg
+	next.LdapAddr = "dc2.example.local:636"
+	is.NoErr(c.Reload(&next))
+	is.Equal(c.config().LdapAddr, "dc2.example.local:636")
+	is.True(first.write.pool.closed)
+}
+
+func TestIdentities(t *testing.T) {
+	base := config.Config{
+		LdapAddr: "dc1.example.local:636", BaseDN: "dc=example,dc=local", PoolSize: 1,
+		BindDN: "cn=writer,dc=example,dc=local", BindPassword: "w",
+	}
+
+	t.Run("reads share the write identity by default", func(t *testing.T) {
+		is := is.New(t)
+		cfg := base
+		c, err := NewClient(&cfg, nil)
+		is.NoErr(err)
+		st := c.current()
+		is.Equal(st.identity(roleRead), st.identity(roleWrite))
+	})
+
+	t.Run("separate read identity has its own pool", func(t *testing.T) {
+		is := is.New(t)
+		cfg := base
+		cfg.ReadBindDN = "cn=reader,dc=example,dc=local"
+		cfg.ReadBindPassword = "r"
+		c, err := NewClient(&cfg, nil)
+		is.NoErr(err)
+		st := c.current()
+		is.Equal(st.read.dn, "cn=reader,dc=example,dc=local")
+		is.Equal(st.write.dn, "cn=writer,dc=example,dc=local")
+		is.True(st.read.pool != st.write.pool)
+
+		c.Close()
+		is.True(st.read.pool.closed)
+		is.True(st.write.pool.closed)
+	})
+}
@@ -0,0 +1,36 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ReadIdentityCanWrite reports whether the read identity may create objects
+// under, or write attributes of, the base DN. It reads AD's constructed
+// allowedChildClassesEffective and allowedAttributesEffective attributes, so
+// it is a best-effort check of the base DN only, not of every container.
+func (c *Client) ReadIdentityCanWrite(ctx context.Context) (bool, error) {
+	conn, err := c.acquire(ctx, roleRead)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	req := ldap.NewSearchRequest(c.config().BaseDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(objectClass=*)", []string{"allowedChildClassesEffective", "allowedAttributesEffective"}, nil)
+	sr, err := conn.Search(req)
+	if err != nil {
+		return false, fmt.Errorf("read effective rights: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return false, nil
+	}
+	return grantsWrite(sr.Entries[0]), nil
+}
+
+func grantsWrite(e *ldap.Entry) bool {
+	return len(e.GetAttributeValues("allowedChildClassesEffective")) > 0 ||
+		len(e.GetAttributeValues("allowedAttributesEffective")) > 0
+}
@@ -0,0 +1,19 @@
+package ldaps
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+)
+
+func TestGrantsWrite(t *testing.T) {
+	is := is.New(t)
+	is.True(!grantsWrite(ldap.NewEntry("dc=example,dc=local", nil)))
+	is.True(grantsWrite(ldap.NewEntry("dc=example,dc=local", map[string][]string{
+		"allowedChildClassesEffective": {"user", "group"},
+	})))
+	is.True(grantsWrite(ldap.NewEntry("dc=example,dc=local", map[string][]string{
+		"allowedAttributesEffective": {"description"},
+	})))
+}
@@ -0,0 +1,38 @@
+package ldaps
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBreachedPassword is returned when a password being set appears in the
+// breached-password corpus.
+var ErrBreachedPassword = errors.New("password appears in a list of breached passwords")
+
+// PasswordScreener checks candidate passwords against known breaches.
+type PasswordScreener interface {
+	Breached(password string) (bool, error)
+}
+
+// WithPasswordScreener screens every password the Client sets or changes.
+func WithPasswordScreener(s PasswordScreener) Option {
+	return func(c *Client) {
+		c.screener = s
+	}
+}
+
+// screenPassword returns ErrBreachedPassword for a breached password. A
+// corpus that cannot be read fails closed.
+func (c *Client) screenPassword(password string) error {
+	if c.screener == nil || password == "" {
+		return nil
+	}
+	breached, err := c.screener.Breached(password)
+	if err != nil {
+		return fmt.Errorf("screen password: %w", err)
+	}
+	if breached {
+		return ErrBreachedPassword
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package ldaps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type fakeScreener struct {
+	breached map[string]bool
+	err      error
+}
+
+func (f fakeScreener) Breached(password string) (bool, error) {
+	return f.breached[password], f.err
+}
+
+func TestScreenPassword(t *testing.T) {
+	screener := fakeScreener{breached: map[string]bool{"Summer2024!": true}}
+
+	t.Run("set refuses a breached password", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{}
+		client := &Client{screener: screener}
+		is.True(errors.Is(client.setUnicodePwd(modifier, "cn=user", "Summer2024!"), ErrBreachedPassword))
+		is.Equal(modifier.calls, 0)
+		is.NoErr(client.setUnicodePwd(modifier, "cn=user", "correct horse battery staple"))
+		is.Equal(modifier.calls, 1)
+	})
+
+	t.Run("change refuses a breached password", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{}
+		client := &Client{screener: screener}
+		is.True(errors.Is(client.changeUnicodePwd(modifier, "cn=svc", "old", "Summer2024!"), ErrBreachedPassword))
+		is.Equal(modifier.calls, 0)
+	})
+
+	t.Run("unreadable corpus fails closed", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{}
+		client := &Client{screener: fakeScreener{err: errors.New("read failed")}}
+		is.True(client.setUnicodePwd(modifier, "cn=user", "anything") != nil)
+		is.Equal(modifier.calls, 0)
+	})
+
+	t.Run("no screener configured", func(t *testing.T) {
+		is := is.New(t)
+		is.NoErr((&Client{}).screenPassword("Summer2024!"))
+	})
+}
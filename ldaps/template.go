@@ -0,0 +1,115 @@
+package ldaps
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// ErrUnknownTemplate is returned by AddUser when the request names a
+// provisioning template that is not configured.
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// uacNormalAccount is userAccountControl for an enabled normal account.
+const uacNormalAccount = 512
+
+var defaultObjectClasses = []string{"top", "person", "organizationalPerson", "user"}
+
+// provisioning is a compiled config.Template.
+type provisioning struct {
+	config.Template
+	computed []computedAttr
+}
+
+type computedAttr struct {
+	attr string
+	tmpl *template.Template
+}
+
+// builtinTemplate reproduces provisioning without any configured template.
+var builtinTemplate = &provisioning{}
+
+func compileTemplates(templates map[string]config.Template) (map[string]*provisioning, error) {
+	out := make(map[string]*provisioning, len(templates))
+	for name, t := range templates {
+		p := &provisioning{Template: t}
+		for attr, text := range t.Computed {
+			tmpl, err := template.New(attr).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: computed %s: %w", name, attr, err)
+			}
+			p.computed = append(p.computed, computedAttr{attr: attr, tmpl: tmpl})
+		}
+		sort.Slice(p.computed, func(i, j int) bool { return p.computed[i].attr < p.computed[j].attr })
+		out[name] = p
+	}
+	return out, nil
+}
+
+// template returns the provisioning template u asks for, the configured
+// default, or the built-in behaviour.
+func (st *clientState) template(u *UserInfo) (*provisioning, error) {
+	name := u.Template
+	if name == "" {
+		name = st.cfg.DefaultTemplate
+	}
+	if name == "" {
+		return builtinTemplate, nil
+	}
+	p, ok := st.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+	}
+	return p, nil
+}
+
+func (p *provisioning) objectClasses() []string {
+	if len(p.ObjectClasses) > 0 {
+		return p.ObjectClasses
+	}
+	return defaultObjectClasses
+}
+
+func (p *provisioning) userAccountControl() int {
+	if p.UserAccountControl != 0 {
+		return p.UserAccountControl
+	}
+	return uacNormalAccount
+}
+
+// defaults returns the template's attribute values for u, sorted by
+// attribute name. Computed values override static defaults.
+func (p *provisioning) defaults(u *UserInfo) ([]ldap.Attribute, error) {
+	byName := make(map[string]ldap.Attribute, len(p.Defaults)+len(p.computed))
+	for attr, vals := range p.Defaults {
+		byName[strings.ToLower(attr)] = ldap.Attribute{Type: attr, Vals: vals}
+	}
+	// Computed values never see the password.
+	view := *u
+	view.Password = ""
+	for _, c := range p.computed {
+		var b strings.Builder
+		if err := c.tmpl.Execute(&b, &view); err != nil {
+			return nil, fmt.Errorf("computed %s: %w", c.attr, err)
+		}
+		// text/template prints missing map keys, e.g. unset custom fields,
+		// as "<no value>".
+		v := strings.TrimSpace(strings.ReplaceAll(b.String(), "<no value>", ""))
+		if v != "" {
+			byName[strings.ToLower(c.attr)] = ldap.Attribute{Type: c.attr, Vals: []string{v}}
+		}
+	}
+
+	attrs := make([]ldap.Attribute, 0, len(byName))
+	for _, a := range byName {
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Type < attrs[j].Type })
+	return attrs, nil
+}
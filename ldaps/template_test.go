@@ -0,0 +1,128 @@
+package ldaps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// withStudentTemplate configures the "student" provisioning template.
+func withStudentTemplate(cfg *config.Config) {
+	cfg.Templates = map[string]config.Template{
+		"student": {
+			ObjectClasses: []string{"top", "person", "organizationalPerson", "user", "uicStudent"},
+			Defaults: map[string][]string{
+				"company":     {"LUG"},
+				"displayName": {"New member"},
+				"scriptPath":  {"logon.bat"},
+			},
+			Computed: map[string]string{
+				"displayName": "{{.GivenName}} {{.Surname}}",
+				"department":  "{{index .CustomAttrs \"college\"}}",
+			},
+			OU:                 "OU=Students",
+			UserAccountControl: 66048,
+			Groups:             []string{"CN=Members,DC=example,DC=local"},
+		},
+	}
+}
+
+func attrValues(req *ldap.AddRequest, name string) []string {
+	for _, a := range req.Attributes {
+		if a.Type == name {
+			return a.Vals
+		}
+	}
+	return nil
+}
+
+func TestSelectTemplate(t *testing.T) {
+	t.Run("none configured keeps built-in behaviour", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		p, err := c.current().template(&UserInfo{})
+		is.NoErr(err)
+		is.Equal(p, builtinTemplate)
+		is.Equal(p.objectClasses(), defaultObjectClasses)
+		is.Equal(p.userAccountControl(), uacNormalAccount)
+	})
+
+	t.Run("default template", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		c.current().cfg.DefaultTemplate = "student"
+		p, err := c.current().template(&UserInfo{})
+		is.NoErr(err)
+		is.Equal(p.OU, "OU=Students")
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		_, err := c.current().template(&UserInfo{Template: "staff"})
+		is.True(errors.Is(err, ErrUnknownTemplate))
+	})
+}
+
+func TestBuildAddRequestWithTemplate(t *testing.T) {
+	t.Run("template fills unset attributes", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		st := c.current()
+		u := &UserInfo{
+			Username: "jdoe", GivenName: "Jane", Surname: "Doe", Template: "student",
+			CustomAttrs: map[string]any{"college": "Engineering"},
+		}
+		p, err := st.template(u)
+		is.NoErr(err)
+
+		req, err := c.buildAddRequest(st, p, "CN=jdoe,OU=Students,dc=example,dc=local", u, &ValidationError{})
+		is.NoErr(err)
+		is.Equal(attrValues(req, "objectClass"), []string{"top", "person", "organizationalPerson", "user", "uicStudent"})
+		is.Equal(attrValues(req, "company"), []string{"LUG"})
+		is.Equal(attrValues(req, "scriptPath"), []string{"logon.bat"})
+		is.Equal(attrValues(req, "department"), []string{"Engineering"})
+		is.Equal(attrValues(req, "displayName"), []string{"Jane Doe"}) // computed beats static
+	})
+
+	t.Run("computed values cannot read the password", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		st := c.current()
+		compiled, err := compileTemplates(map[string]config.Template{"t": {Computed: map[string]string{"info": "{{.Username}}:{{.Password}}"}}})
+		is.NoErr(err)
+		req, err := c.buildAddRequest(st, compiled["t"], "CN=jdoe,dc=example,dc=local", &UserInfo{Username: "jdoe", Password: "S3cret!pw"}, &ValidationError{})
+		is.NoErr(err)
+		is.Equal(attrValues(req, "info"), []string{"jdoe:"})
+	})
+
+	t.Run("request values win", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentTemplate)
+		st := c.current()
+		u := &UserInfo{Username: "jdoe", GivenName: "Jane", DisplayName: "JD", Template: "student"}
+		p, err := st.template(u)
+		is.NoErr(err)
+
+		req, err := c.buildAddRequest(st, p, "CN=jdoe,OU=Students,dc=example,dc=local", u, &ValidationError{})
+		is.NoErr(err)
+		is.Equal(attrValues(req, "displayName"), []string{"JD"})
+		is.Equal(attrValues(req, "department"), nil) // empty result is skipped
+	})
+}
+
+func TestAddToGroup(t *testing.T) {
+	is := is.New(t)
+	modifier := &mockModifier{}
+	client := &Client{}
+	is.NoErr(client.addToGroup(modifier, "CN=Members,DC=example,DC=local", "CN=jdoe,DC=example,DC=local"))
+	is.Equal(modifier.lastRequest.DN, "CN=Members,DC=example,DC=local")
+	change := modifier.lastRequest.Changes[0]
+	is.Equal(change.Operation, uint(ldap.AddAttribute))
+	is.Equal(change.Modification.Type, "member")
+	is.Equal(change.Modification.Vals, []string{"CN=jdoe,DC=example,DC=local"})
+}
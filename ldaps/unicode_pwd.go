@@ -1,20 +1,47 @@
 package ldaps
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"strconv"
 	"unicode/utf16"
 
 	"github.com/go-ldap/ldap/v3"
 )
 
+// ErrUnencrypted is returned when a password operation is attempted on a
+// connection without TLS, which would send the password in clear text.
+var ErrUnencrypted = errors.New("password operations require an encrypted LDAP connection")
+
 type ldapModifier interface {
 	Modify(*ldap.ModifyRequest) error
 }
 
-func (c *Client) setUnicodePwd(conn ldapModifier, dn, password string) error {
+// passwordModifier is an ldapModifier that can report whether it is
+// encrypted.
+type passwordModifier interface {
+	ldapModifier
+	TLSConnectionState() (tls.ConnectionState, bool)
+}
+
+func requireEncrypted(conn passwordModifier) error {
+	if _, ok := conn.TLSConnectionState(); !ok {
+		return ErrUnencrypted
+	}
+	return nil
+}
+
+func (c *Client) setUnicodePwd(conn passwordModifier, dn, password string) error {
 	if password == "" {
 		return nil
 	}
+	if err := requireEncrypted(conn); err != nil {
+		return err
+	}
+	if err := c.screenPassword(password); err != nil {
+		return err
+	}
 	pwdBytes := encodeUnicodePwd(password)
 	mr := ldap.NewModifyRequest(dn, nil)
 	mr.Replace("unicodePwd", []string{string(pwdBytes)})
@@ -24,9 +51,27 @@ func (c *Client) setUnicodePwd(conn ldapModifier, dn, password string) error {
 	return nil
 }
 
-func (c *Client) enableAccount(conn ldapModifier, dn string) error {
+// changeUnicodePwd performs AD's user password change: a single modify that
+// deletes the old value and adds the new one, checked against policy.
+func (c *Client) changeUnicodePwd(conn passwordModifier, dn, oldPassword, newPassword string) error {
+	if err := requireEncrypted(conn); err != nil {
+		return err
+	}
+	if err := c.screenPassword(newPassword); err != nil {
+		return err
+	}
+	mr := ldap.NewModifyRequest(dn, nil)
+	mr.Delete("unicodePwd", []string{string(encodeUnicodePwd(oldPassword))})
+	mr.Add("unicodePwd", []string{string(encodeUnicodePwd(newPassword))})
+	if err := conn.Modify(mr); err != nil {
+		return fmt.Errorf("change unicodePwd failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) setUserAccountControl(conn ldapModifier, dn string, uac int) error {
 	mr := ldap.NewModifyRequest(dn, nil)
-	mr.Replace("userAccountControl", []string{"512"})
+	mr.Replace("userAccountControl", []string{strconv.Itoa(uac)})
 	if err := conn.Modify(mr); err != nil {
 		return fmt.Errorf("enable account failed: %w", err)
 	}
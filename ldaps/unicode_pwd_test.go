@@ -1,6 +1,7 @@
 package ldaps
 
 import (
+	"crypto/tls"
 	"errors"
 	"testing"
 
@@ -12,6 +13,11 @@ type mockModifier struct {
 	lastRequest *ldap.ModifyRequest
 	err         error
 	calls       int
+	plaintext   bool
+}
+
+func (m *mockModifier) TLSConnectionState() (tls.ConnectionState, bool) {
+	return tls.ConnectionState{}, !m.plaintext
 }
 
 func (m *mockModifier) Modify(req *ldap.ModifyRequest) error {
@@ -62,14 +68,45 @@ func TestSetUnicodePwd(t *testing.T) {
 		is.True(err != nil)
 		is.Equal(modifier.calls, 1)
 	})
+
+	t.Run("refuses unencrypted connection", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{plaintext: true}
+		client := &Client{}
+		err := client.setUnicodePwd(modifier, "cn=user", "pw")
+		is.True(errors.Is(err, ErrUnencrypted))
+		is.Equal(modifier.calls, 0)
+	})
+}
+
+func TestChangeUnicodePwd(t *testing.T) {
+	t.Run("deletes old and adds new", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{}
+		client := &Client{}
+		is.NoErr(client.changeUnicodePwd(modifier, "cn=svc", "old", "new"))
+		changes := modifier.lastRequest.Changes
+		is.Equal(len(changes), 2)
+		is.Equal(changes[0].Operation, uint(ldap.DeleteAttribute))
+		is.Equal(changes[1].Operation, uint(ldap.AddAttribute))
+		is.Equal([]byte(changes[1].Modification.Vals[0]), encodeUnicodePwd("new"))
+	})
+
+	t.Run("refuses unencrypted connection", func(t *testing.T) {
+		is := is.New(t)
+		modifier := &mockModifier{plaintext: true}
+		client := &Client{}
+		is.True(errors.Is(client.changeUnicodePwd(modifier, "cn=svc", "old", "new"), ErrUnencrypted))
+		is.Equal(modifier.calls, 0)
+	})
 }
 
-func TestEnableAccount(t *testing.T) {
+func TestSetUserAccountControl(t *testing.T) {
 	t.Run("success writes control", func(t *testing.T) {
 		is := is.New(t)
 		modifier := &mockModifier{}
 		client := &Client{}
-		is.NoErr(client.enableAccount(modifier, "cn=user"))
+		is.NoErr(client.setUserAccountControl(modifier, "cn=user", uacNormalAccount))
 		is.Equal(modifier.calls, 1)
 		is.Equal(modifier.lastRequest.DN, "cn=user")
 		changes := modifier.lastRequest.Changes
@@ -81,7 +118,7 @@ func TestEnableAccount(t *testing.T) {
 		is := is.New(t)
 		modifier := &mockModifier{err: errors.New("failed")}
 		client := &Client{}
-		err := client.enableAccount(modifier, "cn=user")
+		err := client.setUserAccountControl(modifier, "cn=user", uacNormalAccount)
 		is.True(err != nil)
 		is.Equal(modifier.calls, 1)
 	})
@@ -0,0 +1,169 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// userFilter selects user accounts, leaving out contacts and computers.
+const userFilter = "(objectCategory=person)(objectClass=user)"
+
+// ConflictError reports that Value of Attribute, which must be unique, is
+// already used by the entry DN. It describes a client mistake (409).
+type ConflictError struct {
+	Attribute string
+	Value     string
+	DN        string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %q is already used by %s", e.Attribute, e.Value, e.DN)
+}
+
+// Duplicate is a value of a unique attribute shared by several accounts.
+type Duplicate struct {
+	Attribute string   `json:"attribute"`
+	Value     string   `json:"value"`
+	DNs       []string `json:"dns"`
+}
+
+// uniqueAttributes returns the configured attributes no two accounts may
+// share. sAMAccountName is not listed: AD enforces it.
+func (st *clientState) uniqueAttributes() []string {
+	var names []string
+	for _, n := range strings.Split(st.cfg.UniqueAttributes, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// domainBase returns the domain root for domain-wide searches, falling back
+// to BaseDN when the rootDSE does not name it.
+func (c *Client) domainBase(ctx context.Context, conn searcher, st *clientState) string {
+	base, err := readRootDSE(conn, "defaultNamingContext")
+	if err != nil {
+		c.log(ctx).Warn("ldap.domain.unknown", zap.Error(err), zap.String("fallback", st.cfg.BaseDN))
+		return st.cfg.BaseDN
+	}
+	return base
+}
+
+// checkUnique searches the domain for accounts already holding a unique
+// value of req and reports the first one found.
+func (c *Client) checkUnique(ctx context.Context, conn searcher, st *clientState, req *ldap.AddRequest) error {
+	names := st.uniqueAttributes()
+	var clauses strings.Builder
+	for _, name := range names {
+		for _, v := range attributeValues(req, name) {
+			clauses.WriteString("(" + name + "=" + ldap.EscapeFilter(v) + ")")
+		}
+	}
+	if clauses.Len() == 0 {
+		return nil
+	}
+
+	filter := "(&" + userFilter + "(|" + clauses.String() + "))"
+	sr, err := conn.Search(ldap.NewSearchRequest(c.domainBase(ctx, conn, st), ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 10, false,
+		filter, names, nil))
+	if err != nil {
+		return fmt.Errorf("ldap uniqueness search failed: %w", err)
+	}
+	for _, name := range names {
+		for _, v := range attributeValues(req, name) {
+			for _, e := range sr.Entries {
+				for _, existing := range e.GetEqualFoldAttributeValues(name) {
+					if strings.EqualFold(existing, v) {
+						return &ConflictError{Attribute: name, Value: v, DN: e.DN}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func attributeValues(req *ldap.AddRequest, name string) []string {
+	for _, a := range req.Attributes {
+		if strings.EqualFold(a.Type, name) {
+			return a.Vals
+		}
+	}
+	return nil
+}
+
+// addConflict explains an add rejected with entryAlreadyExists: either the
+// sAMAccountName is used elsewhere in the domain or dn itself exists.
+func (c *Client) addConflict(ctx context.Context, conn searcher, st *clientState, dn, username string) error {
+	sr, err := conn.Search(ldap.NewSearchRequest(c.domainBase(ctx, conn, st), ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 10, false,
+		"(sAMAccountName="+ldap.EscapeFilter(username)+")", []string{"sAMAccountName"}, nil))
+	if err == nil && len(sr.Entries) > 0 {
+		return &ConflictError{Attribute: "sAMAccountName", Value: username, DN: sr.Entries[0].DN}
+	}
+	return &ConflictError{Attribute: "distinguishedName", Value: dn, DN: dn}
+}
+
+type pagedSearcher interface {
+	searcher
+	SearchWithPaging(req *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
+}
+
+// Duplicates lists the values of each unique attribute that several
+// accounts share, for cleaning up registrations made before the constraint.
+func (c *Client) Duplicates(ctx context.Context) ([]Duplicate, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	st := c.current()
+	conn, err := c.acquire(ctxTimeout, roleRead)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return c.duplicates(ctx, conn, st)
+}
+
+func (c *Client) duplicates(ctx context.Context, conn pagedSearcher, st *clientState) ([]Duplicate, error) {
+	base := c.domainBase(ctx, conn, st)
+	dups := []Duplicate{}
+	for _, name := range st.uniqueAttributes() {
+		sr, err := conn.SearchWithPaging(ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			"(&"+userFilter+"("+name+"=*))", []string{name}, nil), 500)
+		if err != nil {
+			return nil, fmt.Errorf("ldap search for %s failed: %w", name, err)
+		}
+
+		byValue := make(map[string]*Duplicate)
+		for _, e := range sr.Entries {
+			for _, v := range e.GetEqualFoldAttributeValues(name) {
+				key := strings.ToLower(v)
+				d, ok := byValue[key]
+				if !ok {
+					d = &Duplicate{Attribute: name, Value: v}
+					byValue[key] = d
+				}
+				d.DNs = append(d.DNs, e.DN)
+			}
+		}
+		keys := make([]string, 0, len(byValue))
+		for k, d := range byValue {
+			if len(d.DNs) > 1 {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			d := byValue[k]
+			sort.Strings(d.DNs)
+			dups = append(dups, *d)
+		}
+	}
+	return dups, nil
+}
@@ -0,0 +1,69 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// uniqueSearcher answers the rootDSE and returns entries for every other
+// search.
+// domainDirectory holds entries under the example.local domain root.
+func domainDirectory(entries ...*ldap.Entry) *fakeDirectory {
+	return &fakeDirectory{rootDSE: domainRoot, bases: map[string][]*ldap.Entry{"DC=example,DC=local": entries}}
+}
+
+func TestCheckUnique(t *testing.T) {
+	req := ldap.NewAddRequest("CN=jdoe2,DC=example,DC=local", nil)
+	req.Attribute("sAMAccountName", []string{"jdoe2"})
+	req.Attribute("mail", []string{"JDoe@example.com"})
+	req.Attribute("extensionAttribute2", []string{"650000001"})
+
+	t.Run("conflict names the existing entry", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) { cfg.UniqueAttributes = "mail, extensionAttribute2" })
+		conn := domainDirectory(ldap.NewEntry("CN=jdoe,DC=example,DC=local", map[string][]string{"mail": {"jdoe@example.com"}}))
+
+		err := c.checkUnique(context.Background(), conn, c.current(), req)
+		var conflict *ConflictError
+		is.True(errors.As(err, &conflict))
+		is.Equal(*conflict, ConflictError{Attribute: "mail", Value: "JDoe@example.com", DN: "CN=jdoe,DC=example,DC=local"})
+		is.Equal(conn.requests[1].BaseDN, "DC=example,DC=local")
+		is.Equal(conn.requests[1].Filter, "(&(objectCategory=person)(objectClass=user)(|(mail=JDoe@example.com)(extensionAttribute2=650000001)))")
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) { cfg.UniqueAttributes = "mail" })
+		is.NoErr(c.checkUnique(context.Background(), domainDirectory(), c.current(), req))
+	})
+
+	t.Run("nothing to check", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, func(cfg *config.Config) { cfg.UniqueAttributes = "employeeID" })
+		conn := domainDirectory()
+		is.NoErr(c.checkUnique(context.Background(), conn, c.current(), req))
+		is.Equal(len(conn.requests), 0)
+	})
+}
+
+func TestDuplicates(t *testing.T) {
+	is := is.New(t)
+	c := testClient(t, func(cfg *config.Config) { cfg.UniqueAttributes = "mail,extensionAttribute2" })
+	conn := domainDirectory(
+		ldap.NewEntry("CN=jdoe2,DC=example", map[string][]string{"mail": {"jdoe@example.com"}}),
+		ldap.NewEntry("CN=asmith,DC=example", map[string][]string{"mail": {"asmith@example.com"}}),
+		ldap.NewEntry("CN=jdoe,DC=example", map[string][]string{"mail": {"JDOE@example.com"}, "extensionAttribute2": {"650000001"}}),
+		ldap.NewEntry("CN=nomail,DC=example", map[string][]string{"extensionAttribute2": {"650000002"}}),
+	)
+
+	dups, err := c.duplicates(context.Background(), conn, c.current())
+	is.NoErr(err)
+	is.Equal(dups, []Duplicate{{Attribute: "mail", Value: "jdoe@example.com", DNs: []string{"CN=jdoe,DC=example", "CN=jdoe2,DC=example"}}})
+	is.Equal(conn.requests[1].Filter, "(&(objectCategory=person)(objectClass=user)(mail=*))")
+}
@@ -0,0 +1,108 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/internal/schema"
+)
+
+// UpdateRequest changes the custom fields of an existing member.
+type UpdateRequest struct {
+	// Username is the member's sAMAccountName.
+	Username string `json:"username"`
+	// Custom holds the writable fields configured under attributes to
+	// replace; null or empty values clear a field. Fields left out are kept.
+	Custom map[string]any `json:"custom"`
+}
+
+type updater interface {
+	searcher
+	ldapModifier
+}
+
+// UpdateUser replaces the given custom fields of a member, subject to the
+// same access, type, pattern, length and uniqueness checks as AddUser.
+func (c *Client) UpdateUser(ctx context.Context, r *UpdateRequest) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	st := c.current()
+	conn, err := c.acquire(ctxTimeout, roleWrite)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return c.updateUser(ctx, conn, st, r)
+}
+
+func (c *Client) updateUser(ctx context.Context, conn updater, st *clientState, r *UpdateRequest) error {
+	verr := &ValidationError{}
+	attrs, err := st.schema.EncodeChanges(r.Custom)
+	for _, fe := range schema.FieldErrors(err) {
+		verr.Add("custom."+fe.Field, "%s", fe.Reason)
+	}
+	if len(r.Custom) == 0 {
+		verr.Add("custom", "no fields to update")
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	unique := st.uniqueAttributes()
+	sr, err := conn.Search(ldap.NewSearchRequest(st.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 10, false,
+		"(&"+userFilter+"(sAMAccountName="+ldap.EscapeFilter(r.Username)+"))", unique, nil))
+	if err != nil {
+		return fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(sr.Entries) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoSuchMember, r.Username)
+	}
+	entry := sr.Entries[0]
+
+	// Validate the new values as they would be on creation; only values
+	// the member does not already hold can conflict with another account.
+	probe := ldap.NewAddRequest(entry.DN, nil)
+	fresh := ldap.NewAddRequest(entry.DN, nil)
+	for _, a := range attrs {
+		if len(a.Vals) == 0 {
+			continue
+		}
+		probe.Attribute(a.Type, a.Vals)
+		var added []string
+		for _, v := range a.Vals {
+			if !containsFold(entry.GetEqualFoldAttributeValues(a.Type), v) {
+				added = append(added, v)
+			}
+		}
+		if len(added) > 0 {
+			fresh.Attribute(a.Type, added)
+		}
+	}
+	c.checkAddRequest(ctx, conn, st, probe, verr)
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+	if err := c.checkUnique(ctx, conn, st, fresh); err != nil {
+		return err
+	}
+
+	mr := ldap.NewModifyRequest(entry.DN, nil)
+	fields := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		mr.Replace(a.Type, a.Vals)
+		fields = append(fields, a.Type)
+	}
+	if err := conn.Modify(mr); err != nil {
+		c.log(ctx).Error("ldap modify failed", zap.Error(err), zap.String("dn", entry.DN))
+		return fmt.Errorf("ldap modify failed: %w", err)
+	}
+
+	c.log(ctx).Info("user updated", zap.String("dn", entry.DN), zap.String("username", r.Username), zap.String("attributes", strings.Join(fields, ",")))
+	return nil
+}
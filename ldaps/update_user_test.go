@@ -0,0 +1,100 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// fakeUpdater holds jdoe, finds taken holding studentID 650000001, and
+// records modifies.
+type fakeUpdater struct {
+	modifies []*ldap.ModifyRequest
+}
+
+func (f *fakeUpdater) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	switch {
+	case strings.Contains(req.Filter, "(sAMAccountName=jdoe)"):
+		return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry("CN=jdoe,OU=Students,dc=example,dc=local", map[string][]string{
+			"extensionAttribute2": {"650000002"},
+		})}}, nil
+	case strings.Contains(req.Filter, "(extensionAttribute2=650000001)"):
+		return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry("CN=taken,dc=example,dc=local", map[string][]string{
+			"extensionAttribute2": {"650000001"},
+		})}}, nil
+	}
+	return &ldap.SearchResult{}, nil
+}
+
+func (f *fakeUpdater) Modify(req *ldap.ModifyRequest) error {
+	f.modifies = append(f.modifies, req)
+	return nil
+}
+
+// withStudentFields maps two writable fields, one of them unique, and a
+// read-only one.
+func withStudentFields(cfg *config.Config) {
+	cfg.UniqueAttributes = "extensionAttribute2"
+	cfg.Attributes = []config.AttributeMapping{
+		{Field: "major", Attribute: "extensionAttribute1"},
+		{Field: "studentID", Attribute: "extensionAttribute2", Pattern: "[0-9]{9}"},
+		{Field: "lastLogon", Attribute: "lastLogonTimestamp", Type: config.AttrInt, Access: config.AccessRead},
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replaces and clears fields", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentFields)
+		conn := &fakeUpdater{}
+
+		is.NoErr(c.updateUser(ctx, conn, c.current(), &UpdateRequest{Username: "jdoe", Custom: map[string]any{"major": nil, "studentID": "650000002"}}))
+		is.Equal(len(conn.modifies), 1)
+		is.Equal(conn.modifies[0].DN, "CN=jdoe,OU=Students,dc=example,dc=local")
+		is.Equal(changes(conn.modifies[0]), map[string][]string{
+			"extensionAttribute1": nil,
+			"extensionAttribute2": {"650000002"},
+		})
+	})
+
+	t.Run("read-only and invalid fields", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentFields)
+		conn := &fakeUpdater{}
+
+		err := c.updateUser(ctx, conn, c.current(), &UpdateRequest{Username: "jdoe", Custom: map[string]any{"lastLogon": float64(1), "studentID": "12"}})
+		var verr *ValidationError
+		is.True(errors.As(err, &verr))
+		is.True(strings.Contains(err.Error(), "custom.lastLogon"))
+		is.True(strings.Contains(err.Error(), "custom.studentID"))
+		is.Equal(len(conn.modifies), 0)
+	})
+
+	t.Run("value held by another account", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentFields)
+		conn := &fakeUpdater{}
+
+		err := c.updateUser(ctx, conn, c.current(), &UpdateRequest{Username: "jdoe", Custom: map[string]any{"studentID": "650000001"}})
+		var conflict *ConflictError
+		is.True(errors.As(err, &conflict))
+		is.Equal(conflict.DN, "CN=taken,dc=example,dc=local")
+		is.Equal(len(conn.modifies), 0)
+	})
+
+	t.Run("unknown member", func(t *testing.T) {
+		is := is.New(t)
+		c := testClient(t, withStudentFields)
+
+		err := c.updateUser(ctx, &fakeUpdater{}, c.current(), &UpdateRequest{Username: "ghost", Custom: map[string]any{"major": "CS"}})
+		is.True(errors.Is(err, ErrNoSuchMember))
+	})
+}
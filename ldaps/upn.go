@@ -0,0 +1,107 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// upnSuffix returns the suffix of u's userPrincipalName: the one the caller
+// chose, else UPNSuffix, else the DNS name of BaseDN.
+func (st *clientState) upnSuffix(u *UserInfo) string {
+	if u.UPNSuffix != "" {
+		return u.UPNSuffix
+	}
+	if st.cfg.UPNSuffix != "" {
+		return st.cfg.UPNSuffix
+	}
+	return strings.Join(parseDCParts(st.cfg.BaseDN), ".")
+}
+
+// staticUPNSuffixes returns the suffixes allowed without asking the
+// directory.
+func (st *clientState) staticUPNSuffixes() []string {
+	suffixes := []string{st.upnSuffix(&UserInfo{})}
+	return append(suffixes, st.cfg.UPNSuffixes...)
+}
+
+// upnSuffixes caches the forest's alternative UPN suffixes, the uPNSuffixes
+// of the Partitions container, read on first use.
+type upnSuffixes struct {
+	mu       sync.Mutex
+	loaded   bool
+	suffixes []string
+}
+
+// lookup returns the cached suffixes, reading them through conn the first
+// time. A failed read is retried on the next call.
+func (s *upnSuffixes) lookup(conn searcher) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded {
+		return s.suffixes, nil
+	}
+
+	configDN, err := readRootDSE(conn, "configurationNamingContext")
+	if err != nil {
+		return nil, err
+	}
+	sr, err := conn.Search(ldap.NewSearchRequest("CN=Partitions,"+configDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(objectClass=*)", []string{"uPNSuffixes"}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("read UPN suffixes: %w", err)
+	}
+	if len(sr.Entries) > 0 {
+		s.suffixes = sr.Entries[0].GetAttributeValues("uPNSuffixes")
+	}
+	s.loaded = true
+	return s.suffixes, nil
+}
+
+// allowedUPNSuffixes returns the configured suffixes and those registered
+// in the forest. When the directory cannot be read only the configured
+// ones are returned.
+func (c *Client) allowedUPNSuffixes(ctx context.Context, conn searcher, st *clientState) []string {
+	suffixes := st.staticUPNSuffixes()
+	forest, err := st.upn.lookup(conn)
+	if err != nil {
+		c.log(ctx).Warn("ldap.upn_suffixes.unavailable", zap.Error(err))
+	}
+	return append(suffixes, forest...)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUPNSuffix records a violation when the caller chose a suffix that is
+// neither configured nor registered in the forest.
+func (c *Client) checkUPNSuffix(ctx context.Context, conn searcher, st *clientState, u *UserInfo, verr *ValidationError) {
+	if u.UPNSuffix == "" {
+		return
+	}
+	if allowed := c.allowedUPNSuffixes(ctx, conn, st); !containsFold(allowed, u.UPNSuffix) {
+		verr.Add("upnSuffix", "%q is not an allowed UPN suffix (allowed: %s)", u.UPNSuffix, strings.Join(allowed, ", "))
+	}
+}
+
+// memberFilter matches username as a sAMAccountName or UPN. A UPN with any
+// allowed suffix also matches the account's sAMAccountName, so lookups work
+// whichever suffix the account was created with.
+func (c *Client) memberFilter(ctx context.Context, conn searcher, st *clientState, username string) string {
+	esc := ldap.EscapeFilter(username)
+	prefix, suffix, ok := strings.Cut(username, "@")
+	if ok && prefix != "" && containsFold(c.allowedUPNSuffixes(ctx, conn, st), suffix) {
+		return fmt.Sprintf("(|(userPrincipalName=%s)(sAMAccountName=%s)(sAMAccountName=%s))", esc, esc, ldap.EscapeFilter(prefix))
+	}
+	return fmt.Sprintf("(|(userPrincipalName=%s)(sAMAccountName=%s))", esc, esc)
+}
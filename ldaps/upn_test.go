@@ -0,0 +1,97 @@
+package ldaps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/config"
+)
+
+// partitionsDirectory registers alumni.lug.example on CN=Partitions.
+func partitionsDirectory() *fakeDirectory {
+	const partitions = "CN=Partitions,CN=Configuration,DC=ad,DC=lug,DC=example"
+	return &fakeDirectory{
+		rootDSE: map[string][]string{"configurationNamingContext": {"CN=Configuration,DC=ad,DC=lug,DC=example"}},
+		bases: map[string][]*ldap.Entry{partitions: {
+			ldap.NewEntry(partitions, map[string][]string{"uPNSuffixes": {"alumni.lug.example"}}),
+		}},
+	}
+}
+
+// withUPNSuffix configures the ad.lug.example domain with suffix as the
+// default UPN suffix and acm.example as another allowed one.
+func withUPNSuffix(suffix string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.BaseDN = "dc=ad,dc=lug,dc=example"
+		cfg.UPNSuffix = suffix
+		cfg.UPNSuffixes = []string{"acm.example"}
+	}
+}
+
+func TestUPNSuffix(t *testing.T) {
+	is := is.New(t)
+	is.Equal(testClient(t, withUPNSuffix("")).current().upnSuffix(&UserInfo{}), "ad.lug.example") // derived from the base DN
+	st := testClient(t, withUPNSuffix("lug.example")).current()
+	is.Equal(st.upnSuffix(&UserInfo{}), "lug.example")
+	is.Equal(st.upnSuffix(&UserInfo{UPNSuffix: "acm.example"}), "acm.example")
+}
+
+func TestCheckUPNSuffix(t *testing.T) {
+	tests := []struct {
+		suffix string
+		valid  bool
+	}{
+		{"", true},
+		{"lug.example", true},
+		{"ACM.example", true},
+		{"alumni.lug.example", true},
+		{"evil.example", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.suffix, func(t *testing.T) {
+			is := is.New(t)
+			c := testClient(t, withUPNSuffix("lug.example"))
+			verr := &ValidationError{}
+			c.checkUPNSuffix(context.Background(), partitionsDirectory(), c.current(), &UserInfo{UPNSuffix: tt.suffix}, verr)
+			is.Equal(verr.ErrOrNil() == nil, tt.valid)
+			if !tt.valid {
+				is.True(strings.Contains(verr.Error(), "upnSuffix: \"evil.example\" is not an allowed UPN suffix"))
+			}
+		})
+	}
+}
+
+func TestUPNSuffixesCache(t *testing.T) {
+	is := is.New(t)
+	conn := partitionsDirectory()
+	conn.err = errors.New("boom")
+	s := &upnSuffixes{}
+	_, err := s.lookup(conn)
+	is.True(err != nil)
+
+	conn.err = nil
+	got, err := s.lookup(conn)
+	is.NoErr(err)
+	is.Equal(got, []string{"alumni.lug.example"})
+	n := len(conn.requests)
+	_, _ = s.lookup(conn)
+	is.Equal(len(conn.requests), n) // cached
+}
+
+func TestMemberFilter(t *testing.T) {
+	is := is.New(t)
+	c := testClient(t, withUPNSuffix("lug.example"))
+	st := c.current()
+	conn := partitionsDirectory()
+
+	is.Equal(c.memberFilter(context.Background(), conn, st, "jdoe"), "(|(userPrincipalName=jdoe)(sAMAccountName=jdoe))")
+	is.Equal(c.memberFilter(context.Background(), conn, st, "jdoe@alumni.lug.example"),
+		"(|(userPrincipalName=jdoe@alumni.lug.example)(sAMAccountName=jdoe@alumni.lug.example)(sAMAccountName=jdoe))")
+	is.Equal(c.memberFilter(context.Background(), conn, st, "jdoe@evil.example"),
+		"(|(userPrincipalName=jdoe@evil.example)(sAMAccountName=jdoe@evil.example))")
+}
@@ -0,0 +1,270 @@
+package ldaps
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// Directory limits that hold regardless of the schema.
+const (
+	maxSAMAccountName = 20
+	maxCN             = 64
+)
+
+// minUsername is goberus' own lower bound for new usernames.
+const minUsername = 2
+
+// Violation is one invalid field of a request. Field is the path in the
+// request body, e.g. "username" or "custom.major".
+type Violation struct {
+	Field  string
+	Reason string
+}
+
+// ValidationError lists every violation found in a request, so callers can
+// fix them in one round trip. It describes a client mistake (400).
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Reason)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add records a violation.
+func (e *ValidationError) Add(field, format string, args ...any) {
+	e.Violations = append(e.Violations, Violation{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ErrOrNil returns e if it holds any violation.
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+var samForbidden = regexp.MustCompile(`["/\\\[\]:;|=,+*?<>]`)
+
+// ValidateSAMAccountName checks name against Active Directory's rules for
+// pre-Windows 2000 logon names and records violations under field.
+func ValidateSAMAccountName(name, field string, verr *ValidationError) {
+	if n := utf8.RuneCountInString(name); n > maxSAMAccountName {
+		verr.Add(field, "must be at most %d characters, got %d", maxSAMAccountName, n)
+	}
+	if samForbidden.MatchString(name) {
+		verr.Add(field, `must not contain any of " / \ [ ] : ; | = , + * ? < >`)
+	}
+	if strings.HasSuffix(name, ".") {
+		verr.Add(field, "must not end with a period")
+	}
+	if strings.Trim(name, ". ") == "" {
+		verr.Add(field, "must not consist only of periods and spaces")
+	}
+}
+
+var usernameChars = regexp.MustCompile(`^[A-Za-z0-9@._-]+$`)
+
+// ValidateUsername checks a username goberus is asked to create or rename
+// to: letters, digits, @, ., _ and - only, at least two characters, and
+// then Active Directory's sAMAccountName rules. Violations go under field.
+func ValidateUsername(name, field string, verr *ValidationError) {
+	switch {
+	case !usernameChars.MatchString(name):
+		verr.Add(field, "must contain only letters, numbers, @, ., _, or -")
+	case len(name) < minUsername:
+		verr.Add(field, "must be at least %d characters", minUsername)
+	default:
+		ValidateSAMAccountName(name, field, verr)
+	}
+}
+
+var upnDomain = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// validateUPN checks that upn is prefix@domain with a DNS domain.
+func validateUPN(upn, field string, verr *ValidationError) {
+	prefix, domain, ok := strings.Cut(upn, "@")
+	switch {
+	case !ok || prefix == "":
+		verr.Add(field, "userPrincipalName %q must have the form name@domain", upn)
+	case strings.Contains(domain, "@"):
+		verr.Add(field, "userPrincipalName %q must contain a single @", upn)
+	case strings.ContainsAny(prefix, " \t") || samForbidden.MatchString(prefix):
+		verr.Add(field, "userPrincipalName %q contains characters not allowed in a logon name", upn)
+	case !upnDomain.MatchString(domain):
+		verr.Add(field, "userPrincipalName suffix %q is not a DNS domain", domain)
+	}
+}
+
+// validateAddRequest checks req against directory limits before it is sent:
+// the sAMAccountName and UPN rules, the CN length, and rangeUpper, the
+// maximum length in characters the schema allows for each attribute.
+func (st *clientState) validateAddRequest(req *ldap.AddRequest, limits map[string]int, verr *ValidationError) {
+	for _, attr := range req.Attributes {
+		field := st.fieldFor(attr.Type)
+		switch strings.ToLower(attr.Type) {
+		case "objectclass":
+			continue
+		case "samaccountname":
+			ValidateSAMAccountName(attr.Vals[0], field, verr)
+			continue
+		case "userprincipalname":
+			validateUPN(attr.Vals[0], field, verr)
+		case "cn":
+			if n := utf8.RuneCountInString(attr.Vals[0]); n > maxCN {
+				verr.Add(field, "must be at most %d characters for the CN, got %d", maxCN, n)
+			}
+			continue
+		}
+		upper := limits[strings.ToLower(attr.Type)]
+		if upper <= 0 {
+			continue
+		}
+		for _, v := range attr.Vals {
+			if n := utf8.RuneCountInString(v); n > upper {
+				verr.Add(field, "must be at most %d characters for %s, got %d", upper, attr.Type, n)
+				break
+			}
+		}
+	}
+}
+
+// requestFields maps the attributes AddUser writes to request body fields.
+var requestFields = map[string]string{
+	"cn":                "username",
+	"samaccountname":    "username",
+	"userprincipalname": "username",
+	"givenname":         "givenName",
+	"sn":                "surname",
+	"displayname":       "displayName",
+	"mail":              "mail",
+	"telephonenumber":   "phone",
+	"description":       "description",
+}
+
+// fieldFor returns the request path that supplied attr. Attributes that
+// only a template sets are reported as template.<attribute>.
+func (st *clientState) fieldFor(attr string) string {
+	if f, ok := requestFields[strings.ToLower(attr)]; ok {
+		return f
+	}
+	if f, ok := st.schema.Field(attr); ok {
+		return "custom." + f
+	}
+	return "template." + attr
+}
+
+// attributeLimits caches rangeUpper per attribute, read from the directory
+// schema on first use.
+type attributeLimits struct {
+	mu    sync.Mutex
+	upper map[string]int // lower-cased lDAPDisplayName; 0 when unbounded
+}
+
+type searcher interface {
+	Search(*ldap.SearchRequest) (*ldap.SearchResult, error)
+}
+
+// lookup returns rangeUpper for names, querying the schema for any not yet
+// cached. On error the cached limits are returned with the error, and the
+// missing names are retried on the next call.
+func (l *attributeLimits) lookup(conn searcher, names []string) (map[string]int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.upper == nil {
+		l.upper = make(map[string]int)
+	}
+
+	var missing []string
+	for _, n := range names {
+		key := strings.ToLower(n)
+		if _, ok := l.upper[key]; !ok {
+			missing = append(missing, n)
+		}
+	}
+	if len(missing) > 0 {
+		if err := l.fetch(conn, missing); err != nil {
+			return l.snapshot(), err
+		}
+	}
+	return l.snapshot(), nil
+}
+
+func (l *attributeLimits) fetch(conn searcher, names []string) error {
+	schemaDN, err := readRootDSE(conn, "schemaNamingContext")
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(names)
+	var filter strings.Builder
+	filter.WriteString("(&(objectClass=attributeSchema)(|")
+	for _, n := range names {
+		filter.WriteString("(lDAPDisplayName=" + ldap.EscapeFilter(n) + ")")
+	}
+	filter.WriteString("))")
+	sr, err := conn.Search(ldap.NewSearchRequest(schemaDN, ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 0, 10, false,
+		filter.String(), []string{"lDAPDisplayName", "rangeUpper"}, nil))
+	if err != nil {
+		return fmt.Errorf("read attribute schema: %w", err)
+	}
+
+	for _, n := range names {
+		l.upper[strings.ToLower(n)] = 0 // unbounded unless the schema says otherwise
+	}
+	for _, e := range sr.Entries {
+		upper, err := strconv.Atoi(e.GetAttributeValue("rangeUpper"))
+		if err == nil && upper > 0 {
+			l.upper[strings.ToLower(e.GetAttributeValue("lDAPDisplayName"))] = upper
+		}
+	}
+	return nil
+}
+
+// readRootDSE returns attr of the directory's rootDSE.
+func readRootDSE(conn searcher, attr string) (string, error) {
+	root, err := conn.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 10, false,
+		"(objectClass=*)", []string{attr}, nil))
+	if err != nil {
+		return "", fmt.Errorf("read rootDSE: %w", err)
+	}
+	if len(root.Entries) == 0 || root.Entries[0].GetAttributeValue(attr) == "" {
+		return "", fmt.Errorf("rootDSE has no %s", attr)
+	}
+	return root.Entries[0].GetAttributeValue(attr), nil
+}
+
+func (l *attributeLimits) snapshot() map[string]int {
+	out := make(map[string]int, len(l.upper))
+	for k, v := range l.upper {
+		out[k] = v
+	}
+	return out
+}
+
+// checkAddRequest validates req, discovering attribute limits through conn.
+// When the schema cannot be read, only the fixed limits are enforced.
+func (c *Client) checkAddRequest(ctx context.Context, conn searcher, st *clientState, req *ldap.AddRequest, verr *ValidationError) {
+	names := make([]string, 0, len(req.Attributes))
+	for _, a := range req.Attributes {
+		names = append(names, a.Type)
+	}
+	limits, err := st.limits.lookup(conn, names)
+	if err != nil {
+		c.log(ctx).Warn("ldap.schema.limits_unavailable", zap.Error(err))
+	}
+	st.validateAddRequest(req, limits, verr)
+}
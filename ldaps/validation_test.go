@@ -0,0 +1,153 @@
+package ldaps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matryer/is"
+)
+
+func TestValidateSAMAccountName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"jdoe", ""},
+		{"a-very-long-user-name", "at most 20 characters"},
+		{"j[doe]", "must not contain"},
+		{"j+doe", "must not contain"},
+		{"jdoe.", "must not end with a period"},
+		{"...", "only of periods"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			verr := &ValidationError{}
+			ValidateSAMAccountName(tt.name, "username", verr)
+			if tt.want == "" {
+				is.NoErr(verr.ErrOrNil())
+				return
+			}
+			is.True(strings.Contains(verr.Error(), tt.want))
+		})
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"jdoe", ""},
+		{"jane.doe@lug", ""},
+		{"j", "at least 2 characters"},
+		{"j doe", "only letters, numbers"},
+		{"jdöe", "only letters, numbers"},
+		{"jdoe.", "must not end with a period"},
+		{"a-very-long-user-name", "at most 20 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			verr := &ValidationError{}
+			ValidateUsername(tt.name, "username", verr)
+			if tt.want == "" {
+				is.NoErr(verr.ErrOrNil())
+				return
+			}
+			is.True(strings.Contains(verr.Error(), tt.want))
+		})
+	}
+}
+
+func TestValidateUPN(t *testing.T) {
+	tests := []struct {
+		upn   string
+		valid bool
+	}{
+		{"jdoe@example.local", true},
+		{"jdoe", false},
+		{"@example.local", false},
+		{"j@doe@example.local", false},
+		{"jdoe@exa mple.local", false},
+		{"jdoe@-example.local", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upn, func(t *testing.T) {
+			is := is.New(t)
+			verr := &ValidationError{}
+			validateUPN(tt.upn, "username", verr)
+			is.Equal(verr.ErrOrNil() == nil, tt.valid)
+		})
+	}
+}
+
+func TestValidateAddRequest(t *testing.T) {
+	is := is.New(t)
+	st := testClient(t, nil).current()
+
+	req := ldap.NewAddRequest("CN=x,dc=example,dc=local", nil)
+	req.Attribute("objectClass", []string{"top", "user"})
+	req.Attribute("cn", []string{strings.Repeat("x", 65)})
+	req.Attribute("sAMAccountName", []string{strings.Repeat("x", 21)})
+	req.Attribute("displayName", []string{strings.Repeat("é", 257)})
+	req.Attribute("extensionAttribute1", []string{"Computer Science"})
+	req.Attribute("company", []string{strings.Repeat("c", 65)})
+	req.Attribute("userPrincipalName", []string{"x@example.local"})
+
+	verr := &ValidationError{}
+	st.validateAddRequest(req, map[string]int{"displayname": 256, "extensionattribute1": 1024, "company": 64}, verr)
+
+	var fields []string
+	for _, v := range verr.Violations {
+		fields = append(fields, v.Field)
+	}
+	is.Equal(fields, []string{"username", "username", "displayName", "template.company"})
+	is.True(strings.Contains(verr.Violations[2].Reason, "at most 256 characters for displayName, got 257")) // counted in characters
+}
+
+// schemaDirectory holds the attributeSchema entries of displayName and cn.
+func schemaDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		rootDSE: map[string][]string{"schemaNamingContext": {"CN=Schema,CN=Configuration,DC=example,DC=local"}},
+		bases: map[string][]*ldap.Entry{"CN=Schema,CN=Configuration,DC=example,DC=local": {
+			ldap.NewEntry("CN=Display-Name,CN=Schema", map[string][]string{"lDAPDisplayName": {"displayName"}, "rangeUpper": {"256"}}),
+			ldap.NewEntry("CN=Common-Name,CN=Schema", map[string][]string{"lDAPDisplayName": {"cn"}, "rangeUpper": {"64"}}),
+		}},
+	}
+}
+
+func TestAttributeLimits(t *testing.T) {
+	t.Run("reads the schema once", func(t *testing.T) {
+		is := is.New(t)
+		conn := schemaDirectory()
+		l := &attributeLimits{}
+
+		limits, err := l.lookup(conn, []string{"cn", "displayName", "mail"})
+		is.NoErr(err)
+		is.Equal(limits, map[string]int{"cn": 64, "displayname": 256, "mail": 0})
+		is.Equal(len(conn.requests), 2)
+		is.Equal(conn.requests[1].BaseDN, "CN=Schema,CN=Configuration,DC=example,DC=local")
+
+		_, err = l.lookup(conn, []string{"displayName", "mail"})
+		is.NoErr(err)
+		is.Equal(len(conn.requests), 2) // cached
+	})
+
+	t.Run("failure is retried", func(t *testing.T) {
+		is := is.New(t)
+		conn := schemaDirectory()
+		conn.err = errors.New("boom")
+		l := &attributeLimits{}
+
+		_, err := l.lookup(conn, []string{"cn"})
+		is.True(err != nil)
+
+		conn.err = nil
+		limits, err := l.lookup(conn, []string{"cn"})
+		is.NoErr(err)
+		is.Equal(limits["cn"], 64)
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/lugatuic/goberus/internal/logctx"
+)
+
+// ClientCertIdentity records the caller authenticated by a verified TLS
+// client certificate as the request actor (see logctx.WithActor), so mTLS
+// callers are attributed in logs and audit like any other identity. It must
+// run before Logger so the request logger carries the actor.
+func ClientCertIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			if id := certIdentity(r.TLS.VerifiedChains[0][0]); id != "" {
+				r = r.WithContext(logctx.WithActor(r.Context(), id))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// certIdentity names a client certificate by its subject common name,
+// falling back to its first DNS, URI or email SAN.
+func certIdentity(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return "cert:" + cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return "cert:" + cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return "cert:" + cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		return "cert:" + cert.EmailAddresses[0]
+	default:
+		return ""
+	}
+}
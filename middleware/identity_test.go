@@ -0,0 +1,44 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/lugatuic/goberus/internal/logctx"
+	"github.com/lugatuic/goberus/middleware"
+)
+
+func TestClientCertIdentity(t *testing.T) {
+	var actor string
+	handler := middleware.ClientCertIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = logctx.Actor(r.Context())
+	}))
+
+	t.Run("verified certificate becomes the actor", func(t *testing.T) {
+		is := is.New(t)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "provisioning-bot"}}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		is.Equal(actor, "cert:provisioning-bot")
+	})
+
+	t.Run("unverified certificate is ignored", func(t *testing.T) {
+		is := is.New(t)
+		actor = ""
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		is.Equal(actor, "")
+	})
+}
@@ -0,0 +1,34 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/lugatuic/goberus/internal/logctx"
+	"github.com/lugatuic/goberus/middleware"
+)
+
+func TestLoggerRequestScope(t *testing.T) {
+	is := is.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := middleware.RequestID(middleware.Logger(zap.New(core), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logctx.From(r.Context(), nil).Info("ldap add failed")
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/member", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("ldap add failed").All()
+	is.Equal(len(entries), 1)
+	fields := entries[0].ContextMap()
+	is.Equal(fields["request_id"], "req-123")
+	is.Equal(fields["method"], http.MethodPost)
+	is.Equal(fields["path"], "/v1/member")
+}
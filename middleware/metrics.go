@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// HTTPObserver records the outcome of a served request.
+type HTTPObserver interface {
+	ObserveHTTP(route, method string, status int, d time.Duration)
+}
+
+// Metrics reports each request to obs, labelled by the ServeMux pattern that
+// matched it so that raw paths never become label values.
+func Metrics(obs HTTPObserver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lrw := &responseLogger{ResponseWriter: w}
+		next.ServeHTTP(lrw, r)
+		status := lrw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		obs.ObserveHTTP(route, r.Method, status, time.Since(start))
+	})
+}
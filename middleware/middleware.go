@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/lugatuic/goberus/internal/logctx"
 )
 
 // Logger logs requests around the provided handler.
@@ -30,24 +32,34 @@ func (r *responseLogger) Write(b []byte) (int, error) {
 	return n, err
 }
 
-// Logger logs request timing, status, and response size.
+// Logger logs request timing, status, and response size. It also places a
+// request-scoped logger carrying the request ID, method and path into the
+// request context for downstream handlers and the LDAP client (see logctx).
 func Logger(logger *zap.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		logger.Info("request.start",
+		fields := []zap.Field{
+			zap.String("request_id", r.Header.Get("X-Request-ID")),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
-			zap.String("remote", r.RemoteAddr),
-		)
+		}
+		if traceID := TraceID(r); traceID != "" {
+			fields = append(fields, zap.String("trace_id", traceID))
+		}
+		if actor := logctx.Actor(r.Context()); actor != "" {
+			fields = append(fields, zap.String("actor", actor))
+		}
+		reqLogger := logger.With(fields...)
+		r = r.WithContext(logctx.With(r.Context(), reqLogger))
+
+		reqLogger.Info("request.start", zap.String("remote", r.RemoteAddr))
 		lrw := &responseLogger{ResponseWriter: w}
 		next.ServeHTTP(lrw, r)
 		status := lrw.status
 		if status == 0 {
 			status = http.StatusOK
 		}
-		logger.Info("request.done",
-			zap.String("method", r.Method),
-			zap.String("path", r.URL.Path),
+		reqLogger.Info("request.done",
 			zap.Duration("duration", time.Since(start)),
 			zap.Int("status", status),
 			zap.Int("bytes", lrw.size),
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/lugatuic/goberus/middleware"
+
+// Tracing starts a server span per request, continuing any W3C traceparent
+// sent by the caller. It must run after RequestID so the span can be tagged
+// with the request's correlation ID.
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("http.request_id", r.Header.Get("X-Request-ID")),
+			),
+		)
+		defer span.End()
+
+		// Let callers correlate the request ID they hold with the trace.
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		r = r.WithContext(ctx)
+		lrw := &responseLogger{ResponseWriter: w}
+		next.ServeHTTP(lrw, r)
+
+		status := lrw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// TraceRoute names the current span after the ServeMux pattern that matched
+// the request. Middleware that derives a new request with WithContext hides
+// the mux's pattern from outer layers, so this must wrap the mux directly.
+func TraceRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Pattern == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + r.Pattern)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	})
+}
+
+// TraceID returns the hex trace ID carried by r's context, or "" if none.
+func TraceID(r *http.Request) string {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/lugatuic/goberus/middleware"
+)
+
+func TestTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Run("continues incoming traceparent", func(t *testing.T) {
+		is := is.New(t)
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		var seen string
+		handler := middleware.Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = middleware.TraceID(r)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(seen, traceID)
+		is.True(strings.Contains(rr.Header().Get("traceparent"), traceID))
+	})
+
+	t.Run("no traceparent leaves trace ID empty without a provider", func(t *testing.T) {
+		is := is.New(t)
+		var seen string
+		handler := middleware.Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = middleware.TraceID(r)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		is.Equal(seen, "")
+	})
+}
@@ -3,7 +3,10 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/lugatuic/goberus/handlers"
@@ -13,6 +16,10 @@ import (
 type UserClient interface {
 	GetMemberInfo(ctx context.Context, username string) (*ldaps.MemberInfo, error)
 	AddUser(ctx context.Context, u *ldaps.UserInfo) error
+	GeneratePassword(ctx context.Context, u *ldaps.UserInfo) (string, error)
+	CheckAvailability(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error)
+	MoveUser(ctx context.Context, m *ldaps.MoveRequest) (string, error)
+	UpdateUser(ctx context.Context, r *ldaps.UpdateRequest) error
 }
 
 // HandleGetMember serves GET /v1/member.
@@ -38,6 +45,33 @@ func HandleGetMember(client UserClient, w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
+// HandleAvailability serves GET /v1/member/availability. It reports whether
+// username can be registered and, when it cannot, suggests alternatives
+// derived from givenName, surname and netid.
+func HandleAvailability(client UserClient, w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	req := ldaps.AvailabilityRequest{
+		Username:  strings.TrimSpace(q.Get("username")),
+		GivenName: strings.TrimSpace(q.Get("givenName")),
+		Surname:   strings.TrimSpace(q.Get("surname")),
+		NetID:     strings.TrimSpace(q.Get("netid")),
+	}
+	if req.Username == "" && req.GivenName == "" && req.Surname == "" && req.NetID == "" {
+		http.Error(w, "missing username parameter", http.StatusBadRequest)
+		return nil
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	res, err := client.CheckAvailability(ctxTimeout, req)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(res)
+}
+
 // HandleCreateMember serves POST /v1/member.
 func HandleCreateMember(client UserClient, w http.ResponseWriter, r *http.Request) error {
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit
@@ -54,14 +88,150 @@ func HandleCreateMember(client UserClient, w http.ResponseWriter, r *http.Reques
 	ctxTimeout, cancel := context.WithTimeout(r.Context(), 15*time.Second)
 	defer cancel()
 
+	var generated string
+	if u.GeneratePassword {
+		password, err := client.GeneratePassword(ctxTimeout, &u)
+		if err != nil {
+			return createError(w, err)
+		}
+		generated = password
+		u.Password = password
+		u.MustChangePassword = true
+	}
+
 	if err := client.AddUser(ctxTimeout, &u); err != nil {
-		return err
+		return createError(w, err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	resp := map[string]string{"status": "created"}
+	if generated != "" {
+		// Shown once; never stored or logged.
+		w.Header().Set("Cache-Control", "no-store")
+		resp["password"] = generated
+	}
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]string{"status": "created"}); err != nil {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		return err
 	}
 	return nil
 }
+
+// HandleUpdateMember serves PATCH /v1/member. It replaces the custom fields
+// in the body and keeps the others.
+func HandleUpdateMember(client UserClient, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit
+	var u ldaps.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	u.Username = strings.TrimSpace(u.Username)
+	if u.Username == "" {
+		http.Error(w, "invalid input: username: is required", http.StatusBadRequest)
+		return nil
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	err := client.UpdateUser(ctxTimeout, &u)
+	if errors.Is(err, ldaps.ErrNoSuchMember) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return createError(w, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// HandleMoveMember serves POST /v1/member/move: it renames a member, moves
+// it to another OU, or both, keeping the account and its memberships.
+func HandleMoveMember(client UserClient, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit
+	var m ldaps.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	if err := handlers.SanitizeMove(&m); err != nil {
+		http.Error(w, "invalid input: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	dn, err := client.MoveUser(ctxTimeout, &m)
+	if errors.Is(err, ldaps.ErrNoSuchMember) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		return createError(w, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]string{"status": "moved", "dn": dn})
+}
+
+// HandleGeneratePassword serves POST /v1/password. The optional body names
+// the account (username, display name, template) so the password meets the
+// policy that will apply to it.
+func HandleGeneratePassword(client UserClient, w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit
+	var u ldaps.UserInfo
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	u.Username = strings.TrimSpace(u.Username)
+	u.Template = strings.TrimSpace(u.Template)
+
+	ctxTimeout, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	password, err := client.GeneratePassword(ctxTimeout, &u)
+	if err != nil {
+		return createError(w, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	return json.NewEncoder(w).Encode(map[string]string{"password": password})
+}
+
+// createError answers client mistakes reported by the LDAP client and
+// returns any other error for the caller to handle.
+func createError(w http.ResponseWriter, err error) error {
+	// Checked first: it wraps the error of the step that failed.
+	var partial *ldaps.PartialCreateError
+	if errors.As(err, &partial) {
+		http.Error(w, partial.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	var verr *ldaps.ValidationError
+	if errors.As(err, &verr) {
+		http.Error(w, "invalid input: "+verr.Error(), http.StatusBadRequest)
+		return nil
+	}
+	var policyErr *ldaps.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		http.Error(w, policyErr.Error(), http.StatusUnprocessableEntity)
+		return nil
+	}
+	var conflict *ldaps.ConflictError
+	if errors.As(err, &conflict) {
+		http.Error(w, conflict.Error(), http.StatusConflict)
+		return nil
+	}
+	if errors.Is(err, ldaps.ErrBreachedPassword) {
+		http.Error(w, err.Error()+"; choose a different password", http.StatusUnprocessableEntity)
+		return nil
+	}
+	if errors.Is(err, ldaps.ErrUnknownTemplate) {
+		http.Error(w, "invalid input: "+err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -16,8 +17,12 @@ import (
 )
 
 type fakeUserClient struct {
-	getMemberInfo func(ctx context.Context, username string) (*ldaps.MemberInfo, error)
-	addUser       func(ctx context.Context, u *ldaps.UserInfo) error
+	getMemberInfo     func(ctx context.Context, username string) (*ldaps.MemberInfo, error)
+	addUser           func(ctx context.Context, u *ldaps.UserInfo) error
+	generatePassword  func(ctx context.Context, u *ldaps.UserInfo) (string, error)
+	checkAvailability func(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error)
+	moveUser          func(ctx context.Context, m *ldaps.MoveRequest) (string, error)
+	updateUser        func(ctx context.Context, r *ldaps.UpdateRequest) error
 }
 
 var _ server.UserClient = (*fakeUserClient)(nil)
@@ -36,6 +41,34 @@ func (f *fakeUserClient) AddUser(ctx context.Context, u *ldaps.UserInfo) error {
 	return nil
 }
 
+func (f *fakeUserClient) GeneratePassword(ctx context.Context, u *ldaps.UserInfo) (string, error) {
+	if f.generatePassword != nil {
+		return f.generatePassword(ctx, u)
+	}
+	return "", errors.New("GeneratePassword not stubbed")
+}
+
+func (f *fakeUserClient) CheckAvailability(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error) {
+	if f.checkAvailability != nil {
+		return f.checkAvailability(ctx, req)
+	}
+	return nil, errors.New("CheckAvailability not stubbed")
+}
+
+func (f *fakeUserClient) MoveUser(ctx context.Context, m *ldaps.MoveRequest) (string, error) {
+	if f.moveUser != nil {
+		return f.moveUser(ctx, m)
+	}
+	return "", errors.New("MoveUser not stubbed")
+}
+
+func (f *fakeUserClient) UpdateUser(ctx context.Context, r *ldaps.UpdateRequest) error {
+	if f.updateUser != nil {
+		return f.updateUser(ctx, r)
+	}
+	return errors.New("UpdateUser not stubbed")
+}
+
 func TestHandleGetMember(t *testing.T) {
 	t.Run("missing username", func(t *testing.T) {
 		is := is.New(t)
@@ -104,6 +137,109 @@ func TestHandleCreateMember(t *testing.T) {
 		is.True(strings.Contains(rr.Body.String(), "invalid input"))
 	})
 
+	t.Run("directory validation", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				verr := &ldaps.ValidationError{}
+				verr.Add("custom.shoeSize", "unknown field")
+				verr.Add("displayName", "must be at most 256 characters for displayName, got 300")
+				return verr
+			},
+		}
+		body := strings.NewReader(`{"username":"testuser","custom":{"shoeSize":"42"}}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+		is.True(strings.Contains(rr.Body.String(), "custom.shoeSize: unknown field; displayName: must be at most 256"))
+	})
+
+	t.Run("password policy", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				return &ldaps.PasswordPolicyError{Policy: "domain", Unmet: []string{"must be at least 8 characters"}}
+			},
+		}
+		body := strings.NewReader(`{"username":"testuser","password":"short"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusUnprocessableEntity)
+		is.True(strings.Contains(rr.Body.String(), "must be at least 8 characters"))
+	})
+
+	t.Run("breached password", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				return ldaps.ErrBreachedPassword
+			},
+		}
+		body := strings.NewReader(`{"username":"testuser","password":"Summer2024!"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusUnprocessableEntity)
+		is.True(strings.Contains(rr.Body.String(), "breached"))
+	})
+
+	t.Run("duplicate mail", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				return &ldaps.ConflictError{Attribute: "mail", Value: "jdoe@example.com", DN: "CN=jdoe,DC=example,DC=local"}
+			},
+		}
+		body := strings.NewReader(`{"username":"jdoe2","mail":"jdoe@example.com"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusConflict)
+		is.True(strings.Contains(rr.Body.String(), "CN=jdoe,DC=example,DC=local"))
+	})
+
+	t.Run("partially created", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				return &ldaps.PartialCreateError{
+					DN:        "CN=jdoe,DC=example,DC=local",
+					Remaining: []string{"add to group CN=Lab,DC=example,DC=local"},
+					Err:       &ldaps.ValidationError{},
+				}
+			},
+		}
+		body := strings.NewReader(`{"username":"jdoe"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusInternalServerError)
+		is.True(strings.Contains(rr.Body.String(), "still to do: add to group CN=Lab,DC=example,DC=local"))
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			addUser: func(ctx context.Context, u *ldaps.UserInfo) error {
+				return fmt.Errorf("%w %q", ldaps.ErrUnknownTemplate, u.Template)
+			},
+		}
+		body := strings.NewReader(`{"username":"testuser","template":" staff "}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+		is.True(strings.Contains(rr.Body.String(), `unknown template "staff"`))
+	})
+
 	t.Run("success", func(t *testing.T) {
 		is := is.New(t)
 		var captured *ldaps.UserInfo
@@ -147,7 +283,7 @@ func TestSanitizeUserIntegration(t *testing.T) {
 	defer func() { is.NoErr(resp.Body.Close()) }()
 	is.Equal(resp.StatusCode, http.StatusCreated)
 	is.Equal(captured.Username, "testuser")
-	is.Equal(captured.OrganizationalUnit, "ou=acmusers,dc=acmuic,dc=org")
+	is.Equal(captured.OrganizationalUnit, "OU=ACMUsers,DC=acmuic,DC=org") // case preserved
 }
 
 func TestSanitizeUserIntegration_NoOU(t *testing.T) {
@@ -174,3 +310,218 @@ func TestSanitizeUserIntegration_NoOU(t *testing.T) {
 	is.Equal(captured.Username, "testuser")
 	is.Equal(captured.OrganizationalUnit, "")
 }
+
+func TestGeneratedPasswords(t *testing.T) {
+	client := &fakeUserClient{
+		generatePassword: func(ctx context.Context, u *ldaps.UserInfo) (string, error) {
+			return "Correct-Horse-Battery7", nil
+		},
+	}
+
+	t.Run("create returns the generated password once", func(t *testing.T) {
+		is := is.New(t)
+		var captured *ldaps.UserInfo
+		client.addUser = func(ctx context.Context, u *ldaps.UserInfo) error {
+			captured = u
+			return nil
+		}
+		body := strings.NewReader(`{"username":"testuser","generatePassword":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusCreated)
+		is.Equal(rr.Header().Get("Cache-Control"), "no-store")
+		var resp map[string]string
+		is.NoErr(json.Unmarshal(rr.Body.Bytes(), &resp))
+		is.Equal(resp["password"], "Correct-Horse-Battery7")
+		is.Equal(captured.Password, "Correct-Horse-Battery7")
+		is.True(captured.MustChangePassword)
+	})
+
+	t.Run("password and generatePassword conflict", func(t *testing.T) {
+		is := is.New(t)
+		body := strings.NewReader(`{"username":"testuser","password":"x","generatePassword":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleCreateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+		is.True(strings.Contains(rr.Body.String(), "generatePassword: cannot be combined with password"))
+	})
+
+	t.Run("standalone endpoint accepts an empty body", func(t *testing.T) {
+		is := is.New(t)
+		req := httptest.NewRequest(http.MethodPost, "/v1/password", nil)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleGeneratePassword(client, rr, req))
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(rr.Header().Get("Cache-Control"), "no-store")
+		is.Equal(rr.Body.String(), "{\"password\":\"Correct-Horse-Battery7\"}\n")
+	})
+}
+
+func TestHandleAvailability(t *testing.T) {
+	t.Run("missing parameters", func(t *testing.T) {
+		is := is.New(t)
+		req := httptest.NewRequest(http.MethodGet, "/v1/member/availability", nil)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleAvailability(&fakeUserClient{}, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("taken name with suggestions", func(t *testing.T) {
+		is := is.New(t)
+		var captured ldaps.AvailabilityRequest
+		client := &fakeUserClient{
+			checkAvailability: func(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error) {
+				captured = req
+				return &ldaps.Availability{Username: "jdoe", Reason: "taken", Suggestions: []string{"jane.doe", "jdoe2"}}, nil
+			},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/member/availability?username=jdoe&givenName=Jane&surname=Doe&netid=jdoe4", nil)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleAvailability(client, rr, req))
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(captured, ldaps.AvailabilityRequest{Username: "jdoe", GivenName: "Jane", Surname: "Doe", NetID: "jdoe4"})
+		var resp ldaps.Availability
+		is.NoErr(json.Unmarshal(rr.Body.Bytes(), &resp))
+		is.Equal(resp.Available, false)
+		is.Equal(resp.Suggestions, []string{"jane.doe", "jdoe2"})
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			checkAvailability: func(ctx context.Context, req ldaps.AvailabilityRequest) (*ldaps.Availability, error) {
+				return nil, errors.New("ldap down")
+			},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/v1/member/availability?username=jdoe", nil)
+		is.True(server.HandleAvailability(client, httptest.NewRecorder(), req) != nil)
+	})
+}
+
+func TestHandleMoveMember(t *testing.T) {
+	t.Run("moved", func(t *testing.T) {
+		is := is.New(t)
+		var captured *ldaps.MoveRequest
+		client := &fakeUserClient{
+			moveUser: func(ctx context.Context, m *ldaps.MoveRequest) (string, error) {
+				captured = m
+				return "CN=jsmith,OU=Alumni,DC=example,DC=local", nil
+			},
+		}
+		body := strings.NewReader(`{"username":" JDoe ","newUsername":"JSmith","ou":" OU=Alumni "}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member/move", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleMoveMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(*captured, ldaps.MoveRequest{Username: "jdoe", NewUsername: "jsmith", OrganizationalUnit: "OU=Alumni"})
+		is.True(strings.Contains(rr.Body.String(), `"dn":"CN=jsmith,OU=Alumni,DC=example,DC=local"`))
+	})
+
+	t.Run("nothing to change", func(t *testing.T) {
+		is := is.New(t)
+		req := httptest.NewRequest(http.MethodPost, "/v1/member/move", strings.NewReader(`{"username":"jdoe"}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleMoveMember(&fakeUserClient{}, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("unknown member", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			moveUser: func(ctx context.Context, m *ldaps.MoveRequest) (string, error) {
+				return "", fmt.Errorf("%w: %s", ldaps.ErrNoSuchMember, m.Username)
+			},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/member/move", strings.NewReader(`{"username":"ghost","ou":"OU=Alumni"}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleMoveMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("invalid OU", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			moveUser: func(ctx context.Context, m *ldaps.MoveRequest) (string, error) {
+				verr := &ldaps.ValidationError{}
+				verr.Add("ou", "%q is not under the base DN", m.OrganizationalUnit)
+				return "", verr
+			},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/v1/member/move", strings.NewReader(`{"username":"jdoe","ou":"OU=x,DC=evil"}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleMoveMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+	})
+}
+
+func TestHandleUpdateMember(t *testing.T) {
+	t.Run("updated", func(t *testing.T) {
+		is := is.New(t)
+		var captured *ldaps.UpdateRequest
+		client := &fakeUserClient{
+			updateUser: func(ctx context.Context, r *ldaps.UpdateRequest) error {
+				captured = r
+				return nil
+			},
+		}
+		body := strings.NewReader(`{"username":" jdoe ","custom":{"major":"CS","clubs":null}}`)
+		req := httptest.NewRequest(http.MethodPatch, "/v1/member", body)
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleUpdateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusOK)
+		is.Equal(captured.Username, "jdoe")
+		is.Equal(captured.Custom, map[string]any{"major": "CS", "clubs": nil})
+	})
+
+	t.Run("missing username", func(t *testing.T) {
+		is := is.New(t)
+		req := httptest.NewRequest(http.MethodPatch, "/v1/member", strings.NewReader(`{"custom":{"major":"CS"}}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleUpdateMember(&fakeUserClient{}, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("unknown member", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			updateUser: func(ctx context.Context, r *ldaps.UpdateRequest) error {
+				return fmt.Errorf("%w: %s", ldaps.ErrNoSuchMember, r.Username)
+			},
+		}
+		req := httptest.NewRequest(http.MethodPatch, "/v1/member", strings.NewReader(`{"username":"ghost","custom":{"major":"CS"}}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleUpdateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("read-only field", func(t *testing.T) {
+		is := is.New(t)
+		client := &fakeUserClient{
+			updateUser: func(ctx context.Context, r *ldaps.UpdateRequest) error {
+				verr := &ldaps.ValidationError{}
+				verr.Add("custom.lastLogon", "field is read-only")
+				return verr
+			},
+		}
+		req := httptest.NewRequest(http.MethodPatch, "/v1/member", strings.NewReader(`{"username":"jdoe","custom":{"lastLogon":1}}`))
+		rr := httptest.NewRecorder()
+
+		is.NoErr(server.HandleUpdateMember(client, rr, req))
+		is.Equal(rr.Code, http.StatusBadRequest)
+		is.True(strings.Contains(rr.Body.String(), "custom.lastLogon"))
+	})
+}